@@ -3,7 +3,9 @@ package cmd
 import (
 	"context"
 	"crypto/tls"
+	stderrors "errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -30,11 +32,15 @@ import (
 
 	"github.com/platform-mesh/iam-service/pkg/accountinfo"
 	"github.com/platform-mesh/iam-service/pkg/config"
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
 	"github.com/platform-mesh/iam-service/pkg/directive"
+	"github.com/platform-mesh/iam-service/pkg/fga/breaker"
+	"github.com/platform-mesh/iam-service/pkg/fga/store"
 	"github.com/platform-mesh/iam-service/pkg/graph"
 	"github.com/platform-mesh/iam-service/pkg/keycloak"
 	kcpmiddleware "github.com/platform-mesh/iam-service/pkg/middleware/kcp"
 	keycloakmw "github.com/platform-mesh/iam-service/pkg/middleware/keycloak"
+	"github.com/platform-mesh/iam-service/pkg/redact"
 	"github.com/platform-mesh/iam-service/pkg/resolver"
 	"github.com/platform-mesh/iam-service/pkg/resolver/pm"
 	"github.com/platform-mesh/iam-service/pkg/workspace"
@@ -54,13 +60,24 @@ var serverCmd = &cobra.Command{
 		ctx, _, shutdown := pmcontext.StartContext(log, serviceCfg, defaultCfg.ShutdownTimeout)
 		defer shutdown()
 
+		redact.SetEnabled(serviceCfg.Log.RedactPII)
 		mgr := setupManager(ctx, log)
-		router := setupRouter(ctx, mgr, setupFGAClient())
-		start(serviceCfg, router, ctx, log, defaultCfg.IsLocal)
+		fgaConn := setupFGAConn()
+		fgaClient := openfgav1.NewOpenFGAServiceClient(fgaConn)
+		if defaultCfg.IsLocal && serviceCfg.OpenFGA.LocalModel.Path != "" {
+			writeLocalAuthorizationModel(ctx, fgaClient)
+		}
+		idmClient, err := keycloak.New(ctx, serviceCfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create keycloak client")
+		}
+		router := setupRouter(ctx, mgr, fgaClient, idmClient)
+		// Backend clients are closed only after the HTTP server has drained
+		start(serviceCfg, router, ctx, log, defaultCfg.IsLocal, fgaConn, idmClient)
 	},
 }
 
-func setupRouter(ctx context.Context, mgr mcmanager.Manager, fgaClient openfgav1.OpenFGAServiceClient) *chi.Mux {
+func setupRouter(ctx context.Context, mgr mcmanager.Manager, fgaClient openfgav1.OpenFGAServiceClient, idmClient *keycloak.Service) *chi.Mux {
 	restcfg, err := getRootConfig(mgr)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to get root config")
@@ -90,24 +107,46 @@ func setupRouter(ctx context.Context, mgr mcmanager.Manager, fgaClient openfgav1
 		wsClientFactory,
 		log,
 	)
+	ad.SetResourceRules(serviceCfg.Authorization)
 	dr := graph.DirectiveRoot{
 		Authorized: ad.Authorized,
 	}
 
 	// create Resolver Service
-	idmClient, err := keycloak.New(ctx, serviceCfg)
-	if err != nil {
-		log.Fatal().Err(err).Msg("failed to create keycloak client")
-	}
 	svc, err := pm.NewResolverService(fgaClient, idmClient, serviceCfg, mgr)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to create resolver service")
 	}
-	res := resolver.New(svc, log.ComponentLogger("resolver"))
+	if orgs := serviceCfg.OpenFGA.ValidateModelOrganizations; len(orgs) > 0 {
+		if err := svc.ValidateModel(ctx, orgs); err != nil {
+			log.Fatal().Err(err).Strs("organizations", orgs).Msg("authorization model doesn't match the configured roles")
+		}
+	}
+	if len(serviceCfg.CacheWarmup.Organizations) > 0 {
+		go warmCaches(ctx, svc, ad)
+	}
+	res := resolver.New(svc, ad, log.ComponentLogger("resolver"))
 	router := iamRouter.CreateRouter(defaultCfg, serviceCfg, res, log, mws, dr)
 	return router
 }
 
+// warmCaches loads the caches of the configured organizations in the background, so that
+// the first requests after a deployment don't pay for the lookups
+func warmCaches(ctx context.Context, svc *pm.Service, ad *directive.AuthorizedDirective) {
+	orgs := serviceCfg.CacheWarmup.Organizations
+	log.Info().Strs("organizations", orgs).Msg("Warming up caches")
+
+	if err := ad.WarmCaches(ctx, orgs); err != nil {
+		log.Warn().Err(err).Msg("Cache warm-up stopped")
+		return
+	}
+	if err := svc.WarmCaches(ctx, serviceCfg.CacheWarmup); err != nil {
+		log.Warn().Err(err).Msg("Cache warm-up stopped")
+		return
+	}
+	log.Info().Msg("Warmed up caches")
+}
+
 func getRootConfig(mgr mcmanager.Manager) (*rest.Config, error) {
 	restcfg := rest.CopyConfig(mgr.GetLocalManager().GetConfig())
 	host, err := url.Parse(restcfg.Host)
@@ -119,17 +158,30 @@ func getRootConfig(mgr mcmanager.Manager) (*rest.Config, error) {
 	return restcfg, err
 }
 
-func setupFGAClient() openfgav1.OpenFGAServiceClient {
+func setupFGAConn() *grpc.ClientConn {
 	fgaConn, err := grpc.NewClient(serviceCfg.OpenFGA.GRPCAddr,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(
+			appcontext.RequestIDUnaryClientInterceptor(),
+			breaker.New(serviceCfg.OpenFGA.CircuitBreaker).UnaryClientInterceptor(),
+		),
 	)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to start grpc server")
 	}
+	return fgaConn
+}
 
-	fgaClient := openfgav1.NewOpenFGAServiceClient(fgaConn)
-	return fgaClient
+// writeLocalAuthorizationModel writes the configured authorization model to the local OpenFGA store
+func writeLocalAuthorizationModel(ctx context.Context, fgaClient openfgav1.OpenFGAServiceClient) {
+	localModel := serviceCfg.OpenFGA.LocalModel
+	helper := store.NewFGAStoreHelper(serviceCfg.OpenFGA.StoreCacheTTL)
+	modelID, err := store.WriteLocalAuthorizationModel(ctx, helper, fgaClient, localModel.Organization, localModel.Path)
+	if err != nil {
+		log.Fatal().Err(err).Str("path", localModel.Path).Msg("failed to write local authorization model")
+	}
+	log.Info().Str("modelId", modelID).Str("organization", localModel.Organization).Msg("Wrote local authorization model")
 }
 
 func setupManager(ctx context.Context, log *logger.Logger) mcmanager.Manager {
@@ -179,7 +231,7 @@ func setupManager(ctx context.Context, log *logger.Logger) mcmanager.Manager {
 	return mgr
 }
 
-func start(serviceCfg *config.ServiceConfig, router *chi.Mux, ctx context.Context, log *logger.Logger, isLocal bool) {
+func start(serviceCfg *config.ServiceConfig, router *chi.Mux, ctx context.Context, log *logger.Logger, isLocal bool, closers ...io.Closer) {
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", serviceCfg.Port),
 		Handler:      router,
@@ -200,11 +252,27 @@ func start(serviceCfg *config.ServiceConfig, router *chi.Mux, ctx context.Contex
 	}
 	<-ctx.Done()
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	err := drainAndClose(server, defaultCfg.ShutdownTimeout, closers...)
+	if err != nil {
+		log.Error().Err(err).Msg("Graceful shutdown failed")
+	}
+}
+
+// drainAndClose stops the server from accepting new requests and waits up to timeout for the
+// in-flight ones to finish. The closers are closed afterwards, also when draining timed out,
+// so that no running request loses its backend connections.
+func drainAndClose(server *http.Server, timeout time.Duration, closers ...io.Closer) error {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	err := server.Shutdown(shutdownCtx)
-	if err != nil {
-		log.Panic().Err(err).Msg("Graceful shutdown failed")
+	var errs []error
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, errors.Wrap(err, "failed to drain http server"))
+	}
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, errors.Wrap(err, "failed to close client"))
+		}
 	}
+	return stderrors.Join(errs...)
 }
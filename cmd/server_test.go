@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingCloser struct {
+	closed chan struct{}
+	err    error
+}
+
+func (c *recordingCloser) Close() error {
+	close(c.closed)
+	return c.err
+}
+
+// startBlockingServer serves a single endpoint that blocks until release is closed,
+// simulating a long-running request against the backends
+func startBlockingServer(t *testing.T, release <-chan struct{}) (*http.Server, string, <-chan struct{}) {
+	t.Helper()
+
+	started := make(chan struct{})
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusOK)
+		}),
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(listener) }()
+
+	return server, "http://" + listener.Addr().String(), started
+}
+
+func TestDrainAndClose_WaitsForInFlightRequests(t *testing.T) {
+	release := make(chan struct{})
+	server, url, started := startBlockingServer(t, release)
+
+	requestDone := make(chan int)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			requestDone <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		requestDone <- resp.StatusCode
+	}()
+	<-started
+
+	closer := &recordingCloser{closed: make(chan struct{})}
+	drained := make(chan error)
+	go func() { drained <- drainAndClose(server, 5*time.Second, closer) }()
+
+	// Clients must stay open while the request is still running
+	select {
+	case <-closer.closed:
+		t.Fatal("client closed before in-flight request finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-requestDone)
+	assert.NoError(t, <-drained)
+	<-closer.closed
+}
+
+func TestDrainAndClose_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	server, url, started := startBlockingServer(t, release)
+
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	<-started
+
+	closer := &recordingCloser{closed: make(chan struct{})}
+	start := time.Now()
+	err := drainAndClose(server, 100*time.Millisecond, closer)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	// Clients are closed even though draining gave up
+	<-closer.closed
+}
+
+func TestDrainAndClose_CloserErrors(t *testing.T) {
+	server := &http.Server{}
+	first := &recordingCloser{closed: make(chan struct{}), err: io.ErrClosedPipe}
+	second := &recordingCloser{closed: make(chan struct{})}
+
+	err := drainAndClose(server, time.Second, first, second)
+
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+	<-first.closed
+	<-second.closed
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"math/rand/v2"
 	"time"
 
 	"github.com/jellydator/ttlcache/v3"
@@ -9,12 +10,20 @@ import (
 
 // UserCache provides in-memory caching for user data with TTL support
 type UserCache struct {
-	cache *ttlcache.Cache[string, *graph.User]
-	ttl   time.Duration
+	cache  *ttlcache.Cache[string, *graph.User]
+	ttl    time.Duration
+	jitter float64
 }
 
 // NewUserCache creates a new user cache with the specified TTL
 func NewUserCache(ttl time.Duration) *UserCache {
+	return NewUserCacheWithJitter(ttl, 0)
+}
+
+// NewUserCacheWithJitter creates a new user cache whose entries expire after the specified TTL,
+// randomized by up to the jitter fraction of it in either direction. This staggers the expiry of
+// users cached in a single burst. The jitter is clamped to [0, 1]
+func NewUserCacheWithJitter(ttl time.Duration, jitter float64) *UserCache {
 	cache := ttlcache.New(
 		ttlcache.WithTTL[string, *graph.User](ttl),
 	)
@@ -23,8 +32,9 @@ func NewUserCache(ttl time.Duration) *UserCache {
 	go cache.Start()
 
 	return &UserCache{
-		cache: cache,
-		ttl:   ttl,
+		cache:  cache,
+		ttl:    ttl,
+		jitter: min(max(jitter, 0), 1),
 	}
 }
 
@@ -62,14 +72,14 @@ func (c *UserCache) GetMany(realm string, emails []string) (map[string]*graph.Us
 // Set stores a user in cache with TTL
 func (c *UserCache) Set(realm, email string, user *graph.User) {
 	key := c.buildKey(realm, email)
-	c.cache.Set(key, user, ttlcache.DefaultTTL)
+	c.cache.Set(key, user, c.entryTTL())
 }
 
 // SetMany stores multiple users in cache with TTL
 func (c *UserCache) SetMany(realm string, users map[string]*graph.User) {
 	for email, user := range users {
 		key := c.buildKey(realm, email)
-		c.cache.Set(key, user, ttlcache.DefaultTTL)
+		c.cache.Set(key, user, c.entryTTL())
 	}
 }
 
@@ -113,6 +123,18 @@ type CacheStats struct {
 	Misses  uint64
 }
 
+// entryTTL returns the TTL of a new entry, randomized within the configured jitter
+func (c *UserCache) entryTTL() time.Duration {
+	spread := int64(float64(c.ttl) * c.jitter)
+	if spread <= 0 {
+		return ttlcache.DefaultTTL
+	}
+
+	ttl := c.ttl + time.Duration(rand.Int64N(2*spread+1)-spread)
+	// A TTL of 0 would fall back to the default TTL, keep the entry for the shortest time instead
+	return max(ttl, 1)
+}
+
 // buildKey creates a cache key from realm and email
 func (c *UserCache) buildKey(realm, email string) string {
 	return realm + ":" + email
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -308,3 +309,41 @@ func TestUserCache_SetMany_EmptyMap(t *testing.T) {
 	cache.SetMany("realm1", nil)
 	assert.Equal(t, 0, cache.Size())
 }
+
+func TestUserCache_TTLJitter(t *testing.T) {
+	ttl := time.Hour
+	cache := NewUserCacheWithJitter(ttl, 0.1)
+
+	users := map[string]*graph.User{}
+	for i := range 50 {
+		email := fmt.Sprintf("user%d@example.com", i)
+		users[email] = &graph.User{UserID: fmt.Sprintf("user%d", i), Email: email}
+	}
+	cache.SetMany("realm1", users)
+	cache.Set("realm1", "single@example.com", &graph.User{UserID: "single"})
+
+	ttls := map[time.Duration]bool{}
+	for _, item := range cache.cache.Items() {
+		assert.GreaterOrEqual(t, item.TTL(), 54*time.Minute)
+		assert.LessOrEqual(t, item.TTL(), 66*time.Minute)
+		ttls[item.TTL()] = true
+	}
+	// The entries were cached at once but don't share a single TTL
+	assert.Greater(t, len(ttls), 1)
+}
+
+func TestUserCache_TTLJitter_Disabled(t *testing.T) {
+	ttl := time.Hour
+	cache := NewUserCache(ttl)
+
+	cache.Set("realm1", "test@example.com", &graph.User{UserID: "user123"})
+
+	item := cache.cache.Get("realm1:test@example.com")
+	require.NotNil(t, item)
+	assert.Equal(t, ttl, item.TTL())
+}
+
+func TestNewUserCacheWithJitter_Clamped(t *testing.T) {
+	assert.Equal(t, 0.0, NewUserCacheWithJitter(time.Hour, -0.5).jitter)
+	assert.Equal(t, 1.0, NewUserCacheWithJitter(time.Hour, 2).jitter)
+}
@@ -11,9 +11,25 @@ type IDMConfig struct {
 	ExcludedTenants []string
 }
 
+type OpenFGACircuitBreakerConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+type OpenFGALocalModelConfig struct {
+	Path         string
+	Organization string
+}
+
 type OpenFGAConfig struct {
-	GRPCAddr      string
-	StoreCacheTTL time.Duration
+	GRPCAddr          string
+	StoreCacheTTL     time.Duration
+	MaxTuplesPerWrite int
+	CircuitBreaker    OpenFGACircuitBreakerConfig
+	LocalModel        OpenFGALocalModelConfig
+	// ValidateModelOrganizations are the organizations whose authorization models must define the
+	// relations of all configured roles, the service refuses to start otherwise
+	ValidateModelOrganizations []string
 }
 
 type JWTConfig struct {
@@ -23,19 +39,41 @@ type JWTConfig struct {
 type KeycloakCacheConfig struct {
 	Enabled bool
 	TTL     time.Duration
+	// TTLJitter randomizes the TTL of each cached user by up to this fraction of TTL in either
+	// direction, so that users cached together don't all expire at once. 0 disables jitter.
+	TTLJitter float64
+}
+
+// KeycloakDiscoveryConfig controls how OIDC provider discovery is retried on startup, e.g. while Keycloak is still starting
+type KeycloakDiscoveryConfig struct {
+	// Timeout bounds the total time spent retrying discovery, 0 disables retries
+	Timeout time.Duration
+	// RetryInterval is the delay before the first retry, it doubles after every failed attempt
+	RetryInterval time.Duration
+}
+
+type KeycloakRateLimitConfig struct {
+	RPS   float64
+	Burst int
 }
 
 type KeycloakConfig struct {
-	BaseURL      string
-	ClientID     string
-	ClientSecret string
-	PageSize     int
-	Cache        KeycloakCacheConfig
+	BaseURL         string
+	ClientID        string
+	ClientSecret    string
+	PageSize        int
+	MaxPages        int
+	FetchAllTimeout time.Duration
+	MaxConcurrency  int
+	Cache           KeycloakCacheConfig
+	RateLimit       KeycloakRateLimitConfig
+	Discovery       KeycloakDiscoveryConfig
 }
 
 type PaginationConfig struct {
 	DefaultLimit int
 	DefaultPage  int
+	MaxLimit     int
 }
 
 type SortingConfig struct {
@@ -47,23 +85,65 @@ type RolesConfig struct {
 	FilePath string
 }
 
+type LogConfig struct {
+	RedactPII bool
+}
+
+// AuthorizationConfig lists group resources in the "<group>/<kind>" format of the roles file
+// that are exempt from the regular permission check
+type AuthorizationConfig struct {
+	// PublicResources are readable by all users of the organization without a permission check
+	PublicResources []string
+	// DeniedResources are never accessible through the service
+	DeniedResources []string
+}
+
+// InviteConfig restricts which email addresses can be invited. Domains match exactly,
+// a "*." prefix matches all subdomains of the domain but not the domain itself.
+type InviteConfig struct {
+	// AllowedEmailDomains, if set, are the only domains that can be invited
+	AllowedEmailDomains []string
+	// BlockedEmailDomains can never be invited, even if they are allowed
+	BlockedEmailDomains []string
+}
+
+// CacheWarmupConfig lists what is loaded into the caches on startup
+type CacheWarmupConfig struct {
+	// Organizations whose OpenFGA store and authorization model IDs are resolved. Only the first
+	// five fit into the store cache, further ones are skipped there.
+	Organizations []string
+	// KeycloakUsers loads the users of the organizations' Keycloak realms into the user cache.
+	// Requests take the realm from their token's issuer, the warm-up has no token and expects the
+	// realm to be named after the organization.
+	KeycloakUsers bool
+}
+
 type ServiceConfig struct {
-	Port       int
-	OpenFGA    OpenFGAConfig
-	JWT        JWTConfig
-	IDM        IDMConfig
-	Keycloak   KeycloakConfig
-	Pagination PaginationConfig
-	Sorting    SortingConfig
-	Roles      RolesConfig
+	Port          int
+	OpenFGA       OpenFGAConfig
+	JWT           JWTConfig
+	IDM           IDMConfig
+	Keycloak      KeycloakConfig
+	Pagination    PaginationConfig
+	Sorting       SortingConfig
+	Roles         RolesConfig
+	Log           LogConfig
+	Authorization AuthorizationConfig
+	Invite        InviteConfig
+	CacheWarmup   CacheWarmupConfig
 }
 
 func NewServiceConfig() *ServiceConfig {
 	return &ServiceConfig{
 		Port: 8080,
 		OpenFGA: OpenFGAConfig{
-			GRPCAddr:      "openfga:8081",
-			StoreCacheTTL: 5 * time.Minute,
+			GRPCAddr:          "openfga:8081",
+			StoreCacheTTL:     5 * time.Minute,
+			MaxTuplesPerWrite: 100,
+			CircuitBreaker: OpenFGACircuitBreakerConfig{
+				FailureThreshold: 5,
+				OpenTimeout:      30 * time.Second,
+			},
 		},
 		JWT: JWTConfig{
 			UserIDClaim: "sub",
@@ -72,18 +152,30 @@ func NewServiceConfig() *ServiceConfig {
 			ExcludedTenants: []string{"welcome"},
 		},
 		Keycloak: KeycloakConfig{
-			BaseURL:      "https://portal.dev.local:8443/keycloak",
-			ClientID:     "iam",
-			ClientSecret: os.Getenv("KEYCLOAK_CLIENT_SECRET"),
-			PageSize:     100,
+			BaseURL:         "https://portal.dev.local:8443/keycloak",
+			ClientID:        "iam",
+			ClientSecret:    os.Getenv("KEYCLOAK_CLIENT_SECRET"),
+			PageSize:        100,
+			MaxPages:        100,
+			FetchAllTimeout: 30 * time.Second,
+			MaxConcurrency:  10,
 			Cache: KeycloakCacheConfig{
 				Enabled: true,
 				TTL:     time.Hour,
 			},
+			RateLimit: KeycloakRateLimitConfig{
+				RPS:   0,
+				Burst: 10,
+			},
+			Discovery: KeycloakDiscoveryConfig{
+				Timeout:       time.Minute,
+				RetryInterval: time.Second,
+			},
 		},
 		Pagination: PaginationConfig{
 			DefaultLimit: 10,
 			DefaultPage:  1,
+			MaxLimit:     0,
 		},
 		Sorting: SortingConfig{
 			DefaultField:     "LastName",
@@ -92,6 +184,9 @@ func NewServiceConfig() *ServiceConfig {
 		Roles: RolesConfig{
 			FilePath: "input/roles.yaml",
 		},
+		Log: LogConfig{
+			RedactPII: true,
+		},
 	}
 }
 
@@ -100,6 +195,12 @@ func (c *ServiceConfig) AddFlags(fs *pflag.FlagSet) {
 
 	fs.StringVar(&c.OpenFGA.GRPCAddr, "openfga-grpc-addr", c.OpenFGA.GRPCAddr, "Set OpenFGA gRPC address")
 	fs.DurationVar(&c.OpenFGA.StoreCacheTTL, "openfga-store-cache-ttl", c.OpenFGA.StoreCacheTTL, "Set OpenFGA store cache TTL")
+	fs.IntVar(&c.OpenFGA.MaxTuplesPerWrite, "openfga-max-tuples-per-write", c.OpenFGA.MaxTuplesPerWrite, "Set maximum number of tuples sent to OpenFGA in a single write request")
+	fs.IntVar(&c.OpenFGA.CircuitBreaker.FailureThreshold, "openfga-circuit-breaker-failure-threshold", c.OpenFGA.CircuitBreaker.FailureThreshold, "Set number of consecutive OpenFGA failures that open the circuit breaker (0 disables the breaker)")
+	fs.DurationVar(&c.OpenFGA.CircuitBreaker.OpenTimeout, "openfga-circuit-breaker-open-timeout", c.OpenFGA.CircuitBreaker.OpenTimeout, "Set how long the OpenFGA circuit breaker stays open before probing again")
+	fs.StringVar(&c.OpenFGA.LocalModel.Path, "openfga-local-model-path", c.OpenFGA.LocalModel.Path, "Set path of an OpenFGA authorization model in JSON format written on startup in local mode")
+	fs.StringSliceVar(&c.OpenFGA.ValidateModelOrganizations, "openfga-validate-model-organizations", c.OpenFGA.ValidateModelOrganizations, "Set organizations whose authorization models are checked on startup for the relations of the configured roles")
+	fs.StringVar(&c.OpenFGA.LocalModel.Organization, "openfga-local-model-organization", c.OpenFGA.LocalModel.Organization, "Set organization whose OpenFGA store receives the local authorization model")
 
 	fs.StringVar(&c.JWT.UserIDClaim, "jwt-user-id-claim", c.JWT.UserIDClaim, "Set JWT user id claim")
 	fs.StringSliceVar(&c.IDM.ExcludedTenants, "excluded-tenants", c.IDM.ExcludedTenants, "Set IDM excluded tenants")
@@ -107,12 +208,28 @@ func (c *ServiceConfig) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&c.Keycloak.BaseURL, "keycloak-base-url", c.Keycloak.BaseURL, "Set Keycloak base URL")
 	fs.StringVar(&c.Keycloak.ClientID, "keycloak-client-id", c.Keycloak.ClientID, "Set Keycloak client ID")
 	fs.IntVar(&c.Keycloak.PageSize, "keycloak-page-size", c.Keycloak.PageSize, "Set Keycloak page size")
+	fs.IntVar(&c.Keycloak.MaxPages, "keycloak-max-pages", c.Keycloak.MaxPages, "Set maximum number of Keycloak pages fetched when listing all users (0 disables the limit)")
+	fs.DurationVar(&c.Keycloak.FetchAllTimeout, "keycloak-fetch-all-timeout", c.Keycloak.FetchAllTimeout, "Set timeout for listing all Keycloak users (0 disables the timeout)")
+	fs.IntVar(&c.Keycloak.MaxConcurrency, "keycloak-max-concurrency", c.Keycloak.MaxConcurrency, "Set maximum number of concurrent Keycloak requests per user lookup (0 disables the limit)")
 	fs.BoolVar(&c.Keycloak.Cache.Enabled, "keycloak-cache-enabled", c.Keycloak.Cache.Enabled, "Enable keycloak user cache")
 	fs.DurationVar(&c.Keycloak.Cache.TTL, "keycloak-user-cache-ttl", c.Keycloak.Cache.TTL, "Set keycloak user cache TTL")
+	fs.Float64Var(&c.Keycloak.Cache.TTLJitter, "keycloak-user-cache-ttl-jitter", c.Keycloak.Cache.TTLJitter, "Set the fraction (0-1) by which the keycloak user cache TTL is randomized per user")
+	fs.Float64Var(&c.Keycloak.RateLimit.RPS, "keycloak-rate-limit-rps", c.Keycloak.RateLimit.RPS, "Set keycloak admin API requests per second (0 disables rate limiting)")
+	fs.IntVar(&c.Keycloak.RateLimit.Burst, "keycloak-rate-limit-burst", c.Keycloak.RateLimit.Burst, "Set keycloak admin API rate limit burst")
+	fs.DurationVar(&c.Keycloak.Discovery.Timeout, "keycloak-discovery-timeout", c.Keycloak.Discovery.Timeout, "Set how long keycloak OIDC provider discovery is retried on startup, 0 disables retries")
+	fs.DurationVar(&c.Keycloak.Discovery.RetryInterval, "keycloak-discovery-retry-interval", c.Keycloak.Discovery.RetryInterval, "Set the initial delay between keycloak OIDC provider discovery attempts")
 
 	fs.IntVar(&c.Pagination.DefaultLimit, "pagination-default-limit", c.Pagination.DefaultLimit, "Set default pagination limit")
 	fs.IntVar(&c.Pagination.DefaultPage, "pagination-default-page", c.Pagination.DefaultPage, "Set default pagination page")
+	fs.IntVar(&c.Pagination.MaxLimit, "pagination-max-limit", c.Pagination.MaxLimit, "Set maximum pagination limit (0 disables the limit)")
 	fs.StringVar(&c.Sorting.DefaultField, "sorting-default-field", c.Sorting.DefaultField, "Set default sorting field")
 	fs.StringVar(&c.Sorting.DefaultDirection, "sorting-default-direction", c.Sorting.DefaultDirection, "Set default sorting direction")
 	fs.StringVar(&c.Roles.FilePath, "roles-file-path", c.Roles.FilePath, "Set roles file path")
+	fs.BoolVar(&c.Log.RedactPII, "log-redact-pii", c.Log.RedactPII, "Redact emails in logs and error messages (only disable for local development)")
+	fs.StringSliceVar(&c.Authorization.PublicResources, "authorization-public-resources", c.Authorization.PublicResources, "Set group resources (<group>/<kind>) readable without permission check")
+	fs.StringSliceVar(&c.Authorization.DeniedResources, "authorization-denied-resources", c.Authorization.DeniedResources, "Set group resources (<group>/<kind>) that are always denied")
+	fs.StringSliceVar(&c.Invite.AllowedEmailDomains, "invite-allowed-email-domains", c.Invite.AllowedEmailDomains, "Set email domains that can be invited, *.<domain> matches subdomains (empty allows all)")
+	fs.StringSliceVar(&c.Invite.BlockedEmailDomains, "invite-blocked-email-domains", c.Invite.BlockedEmailDomains, "Set email domains that can never be invited, *.<domain> matches subdomains")
+	fs.StringSliceVar(&c.CacheWarmup.Organizations, "cache-warmup-organizations", c.CacheWarmup.Organizations, "Set organizations whose caches are warmed up on startup (at most 5)")
+	fs.BoolVar(&c.CacheWarmup.KeycloakUsers, "cache-warmup-keycloak-users", c.CacheWarmup.KeycloakUsers, "Load the Keycloak users of the warmed up organizations into the user cache, from the realms named after them")
 }
@@ -16,19 +16,39 @@ func TestNewServiceConfigDefaults(t *testing.T) {
 	require.Equal(t, 8080, cfg.Port)
 	require.Equal(t, "openfga:8081", cfg.OpenFGA.GRPCAddr)
 	require.Equal(t, 5*time.Minute, cfg.OpenFGA.StoreCacheTTL)
+	require.Equal(t, 100, cfg.OpenFGA.MaxTuplesPerWrite)
+	require.Equal(t, 5, cfg.OpenFGA.CircuitBreaker.FailureThreshold)
+	require.Equal(t, 30*time.Second, cfg.OpenFGA.CircuitBreaker.OpenTimeout)
+	require.Empty(t, cfg.OpenFGA.LocalModel.Path)
 	require.Equal(t, "sub", cfg.JWT.UserIDClaim)
 	require.Equal(t, []string{"welcome"}, cfg.IDM.ExcludedTenants)
 	require.Equal(t, "https://portal.dev.local:8443/keycloak", cfg.Keycloak.BaseURL)
 	require.Equal(t, "iam", cfg.Keycloak.ClientID)
 	require.Equal(t, "", cfg.Keycloak.ClientSecret)
 	require.Equal(t, 100, cfg.Keycloak.PageSize)
+	require.Equal(t, 100, cfg.Keycloak.MaxPages)
+	require.Equal(t, 30*time.Second, cfg.Keycloak.FetchAllTimeout)
+	require.Equal(t, 10, cfg.Keycloak.MaxConcurrency)
 	require.True(t, cfg.Keycloak.Cache.Enabled)
 	require.Equal(t, time.Hour, cfg.Keycloak.Cache.TTL)
+	require.Zero(t, cfg.Keycloak.Cache.TTLJitter)
+	require.Equal(t, float64(0), cfg.Keycloak.RateLimit.RPS)
+	require.Equal(t, 10, cfg.Keycloak.RateLimit.Burst)
+	require.Equal(t, time.Minute, cfg.Keycloak.Discovery.Timeout)
+	require.Equal(t, time.Second, cfg.Keycloak.Discovery.RetryInterval)
 	require.Equal(t, 10, cfg.Pagination.DefaultLimit)
 	require.Equal(t, 1, cfg.Pagination.DefaultPage)
+	require.Equal(t, 0, cfg.Pagination.MaxLimit)
 	require.Equal(t, "LastName", cfg.Sorting.DefaultField)
 	require.Equal(t, "ASC", cfg.Sorting.DefaultDirection)
 	require.Equal(t, "input/roles.yaml", cfg.Roles.FilePath)
+	require.True(t, cfg.Log.RedactPII)
+	require.Empty(t, cfg.Authorization.PublicResources)
+	require.Empty(t, cfg.Authorization.DeniedResources)
+	require.Empty(t, cfg.Invite.AllowedEmailDomains)
+	require.Empty(t, cfg.Invite.BlockedEmailDomains)
+	require.Empty(t, cfg.CacheWarmup.Organizations)
+	require.False(t, cfg.CacheWarmup.KeycloakUsers)
 }
 
 func TestAddFlagsParsesIntoServiceConfig(t *testing.T) {
@@ -42,37 +62,79 @@ func TestAddFlagsParsesIntoServiceConfig(t *testing.T) {
 		"--port=9090",
 		"--openfga-grpc-addr=fga.example:9443",
 		"--openfga-store-cache-ttl=30s",
+		"--openfga-max-tuples-per-write=50",
+		"--openfga-circuit-breaker-failure-threshold=3",
+		"--openfga-circuit-breaker-open-timeout=1m",
+		"--openfga-local-model-path=/tmp/model.json",
+		"--openfga-local-model-organization=dev-org",
 		"--jwt-user-id-claim=user_id",
 		"--excluded-tenants=welcome,tenant-a",
 		"--keycloak-base-url=https://keycloak.example.local",
 		"--keycloak-client-id=test-client",
 		"--keycloak-page-size=200",
+		"--keycloak-max-pages=20",
+		"--keycloak-fetch-all-timeout=2m",
+		"--keycloak-max-concurrency=4",
 		"--keycloak-cache-enabled=false",
 		"--keycloak-user-cache-ttl=90m",
+		"--keycloak-user-cache-ttl-jitter=0.1",
+		"--keycloak-rate-limit-rps=25.5",
+		"--keycloak-rate-limit-burst=5",
+		"--keycloak-discovery-timeout=2m",
+		"--keycloak-discovery-retry-interval=500ms",
 		"--pagination-default-limit=50",
 		"--pagination-default-page=3",
+		"--pagination-max-limit=500",
 		"--sorting-default-field=FirstName",
 		"--sorting-default-direction=DESC",
 		"--roles-file-path=/tmp/roles.yaml",
+		"--log-redact-pii=false",
+		"--authorization-public-resources=apps/Deployment,core.platform-mesh.io/Account",
+		"--authorization-denied-resources=example.io/Secret",
+		"--invite-allowed-email-domains=example.com,*.example.com",
+		"--invite-blocked-email-domains=guest.example.com",
+		"--cache-warmup-organizations=org-a,org-b",
+		"--cache-warmup-keycloak-users",
 	})
 	require.NoError(t, err)
 
 	require.Equal(t, 9090, cfg.Port)
 	require.Equal(t, "fga.example:9443", cfg.OpenFGA.GRPCAddr)
 	require.Equal(t, 30*time.Second, cfg.OpenFGA.StoreCacheTTL)
+	require.Equal(t, 50, cfg.OpenFGA.MaxTuplesPerWrite)
+	require.Equal(t, 3, cfg.OpenFGA.CircuitBreaker.FailureThreshold)
+	require.Equal(t, time.Minute, cfg.OpenFGA.CircuitBreaker.OpenTimeout)
+	require.Equal(t, "/tmp/model.json", cfg.OpenFGA.LocalModel.Path)
+	require.Equal(t, "dev-org", cfg.OpenFGA.LocalModel.Organization)
 	require.Equal(t, "user_id", cfg.JWT.UserIDClaim)
 	require.Equal(t, []string{"welcome", "tenant-a"}, cfg.IDM.ExcludedTenants)
 	require.Equal(t, "https://keycloak.example.local", cfg.Keycloak.BaseURL)
 	require.Equal(t, "test-client", cfg.Keycloak.ClientID)
 	require.Equal(t, "", cfg.Keycloak.ClientSecret)
 	require.Equal(t, 200, cfg.Keycloak.PageSize)
+	require.Equal(t, 20, cfg.Keycloak.MaxPages)
+	require.Equal(t, 2*time.Minute, cfg.Keycloak.FetchAllTimeout)
+	require.Equal(t, 4, cfg.Keycloak.MaxConcurrency)
 	require.False(t, cfg.Keycloak.Cache.Enabled)
 	require.Equal(t, 90*time.Minute, cfg.Keycloak.Cache.TTL)
+	require.Equal(t, 0.1, cfg.Keycloak.Cache.TTLJitter)
+	require.Equal(t, 25.5, cfg.Keycloak.RateLimit.RPS)
+	require.Equal(t, 5, cfg.Keycloak.RateLimit.Burst)
+	require.Equal(t, 2*time.Minute, cfg.Keycloak.Discovery.Timeout)
+	require.Equal(t, 500*time.Millisecond, cfg.Keycloak.Discovery.RetryInterval)
 	require.Equal(t, 50, cfg.Pagination.DefaultLimit)
 	require.Equal(t, 3, cfg.Pagination.DefaultPage)
+	require.Equal(t, 500, cfg.Pagination.MaxLimit)
 	require.Equal(t, "FirstName", cfg.Sorting.DefaultField)
 	require.Equal(t, "DESC", cfg.Sorting.DefaultDirection)
 	require.Equal(t, "/tmp/roles.yaml", cfg.Roles.FilePath)
+	require.False(t, cfg.Log.RedactPII)
+	require.Equal(t, []string{"apps/Deployment", "core.platform-mesh.io/Account"}, cfg.Authorization.PublicResources)
+	require.Equal(t, []string{"example.io/Secret"}, cfg.Authorization.DeniedResources)
+	require.Equal(t, []string{"example.com", "*.example.com"}, cfg.Invite.AllowedEmailDomains)
+	require.Equal(t, []string{"guest.example.com"}, cfg.Invite.BlockedEmailDomains)
+	require.Equal(t, []string{"org-a", "org-b"}, cfg.CacheWarmup.Organizations)
+	require.True(t, cfg.CacheWarmup.KeycloakUsers)
 }
 
 func TestNewServiceConfigReadsKeycloakClientSecretFromEnv(t *testing.T) {
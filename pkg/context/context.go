@@ -15,6 +15,8 @@ const (
 	kcpContextKey contextKey = "KCPContext"
 	// clusterIdContextKey is the key for storing Cluster ID
 	clusterIdContextKey contextKey = "clusterId"
+	// authorizationModelIdContextKey is the key for storing a pinned OpenFGA authorization model ID
+	authorizationModelIdContextKey contextKey = "authorizationModelId"
 )
 
 // KCPContext holds KCP-related user information
@@ -43,6 +45,19 @@ func GetKCPContext(ctx context.Context) (KCPContext, error) {
 	return kcpCtx, nil
 }
 
+// GetIDMTenant retrieves the IDM tenant of the KCP context from the request context.
+// An empty tenant is rejected, as it would address the wrong Keycloak realm.
+func GetIDMTenant(ctx context.Context) (string, error) {
+	kcpCtx, err := GetKCPContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if kcpCtx.IDMTenant == "" {
+		return "", errors.New("IDM tenant must not be empty")
+	}
+	return kcpCtx.IDMTenant, nil
+}
+
 // SetClusterId stores the Cluster ID in the request context
 func SetClusterId(ctx context.Context, clusterId string) context.Context {
 	return context.WithValue(ctx, clusterIdContextKey, clusterId)
@@ -60,3 +75,17 @@ func GetClusterId(ctx context.Context) (string, error) {
 	}
 	return clusterId, nil
 }
+
+// SetAuthorizationModelId pins the OpenFGA authorization model used for the request
+// The authorized directive pins the model the caller's permission was checked with.
+// Without a pinned model the latest model of the organization's store is used
+func SetAuthorizationModelId(ctx context.Context, modelId string) context.Context {
+	return context.WithValue(ctx, authorizationModelIdContextKey, modelId)
+}
+
+// GetAuthorizationModelId retrieves the pinned OpenFGA authorization model ID from the request context
+// Returns false if no model is pinned
+func GetAuthorizationModelId(ctx context.Context) (string, bool) {
+	modelId, ok := ctx.Value(authorizationModelIdContextKey).(string)
+	return modelId, ok && modelId != ""
+}
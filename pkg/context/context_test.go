@@ -32,6 +32,20 @@ func TestKCPContext(t *testing.T) {
 	assert.Contains(t, err.Error(), "kcp user context not found in context")
 }
 
+func TestGetIDMTenant(t *testing.T) {
+	ctx := SetKCPContext(context.Background(), KCPContext{IDMTenant: "test-tenant"})
+	tenant, err := GetIDMTenant(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "test-tenant", tenant)
+
+	ctx = SetKCPContext(context.Background(), KCPContext{OrganizationName: "test-org"})
+	_, err = GetIDMTenant(ctx)
+	assert.EqualError(t, err, "IDM tenant must not be empty")
+
+	_, err = GetIDMTenant(context.Background())
+	assert.ErrorContains(t, err, "kcp user context not found in context")
+}
+
 func TestClusterId(t *testing.T) {
 	ctx := context.Background()
 
@@ -76,3 +90,19 @@ func TestContextChaining(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, clusterId, retrievedClusterId)
 }
+
+func TestAuthorizationModelId(t *testing.T) {
+	ctx := context.Background()
+
+	// Test getting model ID from empty context
+	_, ok := GetAuthorizationModelId(ctx)
+	assert.False(t, ok)
+
+	// An empty model ID doesn't pin a model
+	_, ok = GetAuthorizationModelId(SetAuthorizationModelId(ctx, ""))
+	assert.False(t, ok)
+
+	modelId, ok := GetAuthorizationModelId(SetAuthorizationModelId(ctx, "model-123"))
+	assert.True(t, ok)
+	assert.Equal(t, "model-123", modelId)
+}
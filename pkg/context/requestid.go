@@ -0,0 +1,42 @@
+package context
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is the header used to propagate the request ID to downstream services.
+// It matches the header read by the platform-mesh request id middleware.
+const RequestIDHeader = "X-Request-Id"
+
+// WithOutgoingRequestID adds the request ID from the context to the outgoing gRPC metadata
+// The context is returned unchanged if no request ID is present
+func WithOutgoingRequestID(ctx context.Context) context.Context {
+	requestID := middleware.GetRequestId(ctx)
+	if requestID == "" {
+		return ctx
+	}
+	// gRPC metadata keys are lowercase
+	return metadata.AppendToOutgoingContext(ctx, strings.ToLower(RequestIDHeader), requestID)
+}
+
+// RequestIDUnaryClientInterceptor propagates the request ID on every outgoing unary gRPC call
+func RequestIDUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(WithOutgoingRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// SetRequestIDHeader sets the request ID from the context as header on an outgoing HTTP request
+// Its signature matches the request editor callbacks of the generated Keycloak client
+func SetRequestIDHeader(ctx context.Context, req *http.Request) error {
+	if requestID := middleware.GetRequestId(ctx); requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	return nil
+}
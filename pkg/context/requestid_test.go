@@ -0,0 +1,56 @@
+package context
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestIDUnaryClientInterceptor(t *testing.T) {
+	interceptor := RequestIDUnaryClientInterceptor()
+
+	t.Run("propagates request id", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), keys.RequestIdCtxKey, "req-123")
+
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, ok := metadata.FromOutgoingContext(ctx)
+			require.True(t, ok)
+			assert.Equal(t, []string{"req-123"}, md.Get(RequestIDHeader))
+			return nil
+		}
+
+		err := interceptor(ctx, "/openfga.v1.OpenFGAService/Check", nil, nil, nil, invoker)
+		assert.NoError(t, err)
+	})
+
+	t.Run("no request id", func(t *testing.T) {
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			assert.Empty(t, md.Get(RequestIDHeader))
+			return nil
+		}
+
+		err := interceptor(context.Background(), "/openfga.v1.OpenFGAService/Check", nil, nil, nil, invoker)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSetRequestIDHeader(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "http://keycloak.local", nil)
+	require.NoError(t, err)
+
+	err = SetRequestIDHeader(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Empty(t, req.Header.Get(RequestIDHeader))
+
+	ctx := context.WithValue(context.Background(), keys.RequestIdCtxKey, "req-123")
+	err = SetRequestIDHeader(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "req-123", req.Header.Get(RequestIDHeader))
+}
@@ -4,6 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql"
@@ -20,20 +25,85 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/platform-mesh/iam-service/pkg/accountinfo"
+	"github.com/platform-mesh/iam-service/pkg/config"
 	appcontext "github.com/platform-mesh/iam-service/pkg/context"
 	"github.com/platform-mesh/iam-service/pkg/fga/store"
 	"github.com/platform-mesh/iam-service/pkg/fga/tuples"
 	"github.com/platform-mesh/iam-service/pkg/graph"
 	"github.com/platform-mesh/iam-service/pkg/metrics"
+	"github.com/platform-mesh/iam-service/pkg/redact"
 	"github.com/platform-mesh/iam-service/pkg/workspace"
 )
 
+// sanitizeUser returns a redacted version of the FGA user for logging, keeping the type prefix
+// to avoid logging PII information
+func sanitizeUser(user string) string {
+	if subjectType, id, found := strings.Cut(user, ":"); found {
+		return subjectType + ":" + redact.Email(id)
+	}
+	return redact.Email(user)
+}
+
+// callerSubject returns the FGA user the caller is checked as. Callers with a mail are users, tokens
+// without one, e.g. from a client credentials grant, identify service accounts by subject. Service accounts
+// are only checked as such if the authorization model defines the type, OpenFGA rejects checks of
+// unknown types. Otherwise they are checked as the user of their mail as before, which is denied.
+func (a AuthorizedDirective) callerSubject(ctx context.Context, storeID, modelID string, token jwt.WebToken) (string, error) {
+	if token.Mail != "" {
+		return tuples.User(token.Mail), nil
+	}
+	if token.Subject == "" {
+		return "", errors.New("token identifies neither a user nor a service account")
+	}
+
+	defined, err := a.modelDefinesType(ctx, storeID, modelID, tuples.ServiceAccountType)
+	if err != nil {
+		return "", err
+	}
+	if !defined {
+		return tuples.User(token.Mail), nil
+	}
+	return tuples.Subject(tuples.ServiceAccountType, token.Subject), nil
+}
+
+// modelDefinesType reports whether the authorization model defines the given type. Models are immutable,
+// so the answer is cached by model ID.
+func (a AuthorizedDirective) modelDefinesType(ctx context.Context, storeID, modelID, typeName string) (bool, error) {
+	key := modelID + "/" + typeName
+	if defined, ok := a.modelTypes.Load(key); ok {
+		return defined.(bool), nil
+	}
+
+	res, err := a.fga.ReadAuthorizationModel(ctx, &openfgav1.ReadAuthorizationModelRequest{StoreId: storeID, Id: modelID})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to read authorization model %s", modelID)
+	}
+
+	defined := slices.ContainsFunc(res.GetAuthorizationModel().GetTypeDefinitions(), func(td *openfgav1.TypeDefinition) bool {
+		return td.GetType() == typeName
+	})
+	a.modelTypes.Store(key, defined)
+	return defined, nil
+}
+
 type AuthorizedDirective struct {
 	fga      openfgav1.OpenFGAServiceClient
 	helper   store.StoreHelper
 	air      accountinfo.Retriever
 	wcClient workspace.ClientFactory
 	log      *logger.Logger
+
+	// modelTypes caches whether an authorization model defines a type, keyed by "<modelID>/<type>"
+	modelTypes *sync.Map
+
+	publicResources map[string]bool
+	deniedResources map[string]bool
+}
+
+// readPermissions are the permissions granted on public resources without a check
+var readPermissions = map[string]bool{
+	"get_iam_roles": true,
+	"get_iam_users": true,
 }
 
 func NewAuthorizedDirective(oc openfgav1.OpenFGAServiceClient, air accountinfo.Retriever, storeTTL time.Duration, cf workspace.ClientFactory, log *logger.Logger) *AuthorizedDirective {
@@ -43,6 +113,8 @@ func NewAuthorizedDirective(oc openfgav1.OpenFGAServiceClient, air accountinfo.R
 		air:      air,
 		wcClient: cf,
 		log:      log,
+
+		modelTypes: &sync.Map{},
 	}
 }
 
@@ -54,31 +126,206 @@ func NewAuthorizedDirectiveWithFactory(oc openfgav1.OpenFGAServiceClient, air ac
 		helper:   store.NewFGAStoreHelper(storeTTL),
 		air:      air,
 		wcClient: clientFactory,
+
+		modelTypes: &sync.Map{},
 	}
 }
 
+// SetResourceRules configures the group resources that bypass the permission check for reading
+// or are always denied. It must be called before the directive is used.
+func (a *AuthorizedDirective) SetResourceRules(cfg config.AuthorizationConfig) {
+	a.publicResources = toSet(cfg.PublicResources)
+	a.deniedResources = toSet(cfg.DeniedResources)
+}
+
+// WarmCaches resolves the store and authorization model IDs of the organizations ahead of the first request
+func (a *AuthorizedDirective) WarmCaches(ctx context.Context, orgIDs []string) error {
+	return store.WarmUp(ctx, a.helper, a.fga, orgIDs)
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// groupResource returns the group resource of the context in the "<group>/<kind>" format of the roles file
+func groupResource(rctx *graph.ResourceContext) string {
+	return rctx.Group + "/" + rctx.Kind
+}
+
+// isPublicRead reports whether the permission is a read permission on a public resource
+func (a AuthorizedDirective) isPublicRead(rctx *graph.ResourceContext, permission string) bool {
+	return readPermissions[permission] && a.publicResources[groupResource(rctx)]
+}
+
+// Denials are reported with these sentinels internally. gqlgen sets the path of the errors returned by
+// a directive, so Authorized reports them to the client as new errors on every call.
+var (
+	errUnauthorized      = errors.New("unauthorized")
+	errResourceNotExists = errors.New("resource does not exist")
+)
+
 func (a AuthorizedDirective) Authorized(ctx context.Context, _ any, next graphql.Resolver, permission string) (any, error) {
 	a.log.Debug().Msg("Authorized directive called with permission: " + permission)
 
+	token, kctx, err := a.callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldCtx := graphql.GetFieldContext(ctx)
+	rctx, err := extractResourceContextFromArguments(fieldCtx.Args)
+	if err != nil { // coverage-ignore
+		return nil, err
+	}
+	if rctx == nil {
+		return nil, gqlerror.Errorf("resource context is nil")
+	}
+
+	ctx, err = a.authorize(ctx, token, kctx, rctx, permission)
+	if errors.Is(err, errUnauthorized) {
+		return nil, gqlerror.Errorf("unauthorized")
+	}
+	if errors.Is(err, errResourceNotExists) {
+		return nil, gqlerror.Errorf("resource does not exist")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return next(ctx)
+}
+
+// CanI reports whether the calling user has the given permission on the resource.
+// Denials are reported as false without a reason, so callers can't tell a missing
+// permission from a resource that doesn't exist or belongs to another organization.
+// Failures while evaluating the permission are returned as error.
+func (a AuthorizedDirective) CanI(ctx context.Context, rctx graph.ResourceContext, permission string) (bool, error) {
+	if rctx.Resource == nil {
+		return false, gqlerror.Errorf("resource is required")
+	}
+
+	token, kctx, err := a.callerFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = a.authorize(ctx, token, kctx, &rctx, permission)
+	if errors.Is(err, errUnauthorized) || errors.Is(err, errResourceNotExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CheckPermissions reports for each of the given permissions whether the calling user has it on the resource.
+// All permissions are checked in a single BatchCheck request. As in CanI, a resource that doesn't exist or
+// belongs to another organization is reported as all permissions denied.
+func (a AuthorizedDirective) CheckPermissions(ctx context.Context, rctx graph.ResourceContext, permissions []string) (map[string]bool, error) {
+	if rctx.Resource == nil {
+		return nil, gqlerror.Errorf("resource is required")
+	}
+
+	token, kctx, err := a.callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions = slices.Compact(slices.Sorted(slices.Values(permissions)))
+	denied := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		denied[permission] = false
+	}
+	if len(permissions) == 0 || a.deniedResources[groupResource(&rctx)] {
+		return denied, nil
+	}
+
+	ctx, ai, err := a.resolveResource(ctx, kctx, &rctx)
+	if errors.Is(err, errUnauthorized) || errors.Is(err, errResourceNotExists) {
+		return denied, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Read permissions on public resources are granted without asking OpenFGA
+	var toCheck []string
+	publicReads := map[string]bool{}
+	for _, permission := range permissions {
+		if a.isPublicRead(&rctx, permission) {
+			publicReads[permission] = true
+		} else {
+			toCheck = append(toCheck, permission)
+		}
+	}
+	if len(toCheck) == 0 {
+		return publicReads, nil
+	}
+
+	allowed, err := a.testPermissions(ctx, ai, &rctx, toCheck, token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to test permissions")
+	}
+	maps.Copy(allowed, publicReads)
+	return allowed, nil
+}
+
+// callerFromContext retrieves the web token and kcp context of the calling user
+func (a AuthorizedDirective) callerFromContext(ctx context.Context) (jwt.WebToken, appcontext.KCPContext, error) {
 	token, err := pmcontext.GetWebTokenFromContext(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get web token from context")
+		return jwt.WebToken{}, appcontext.KCPContext{}, errors.Wrap(err, "failed to get web token from context")
 	}
 
 	kctx, err := appcontext.GetKCPContext(ctx)
 	if err != nil { // coverage-ignore
-		return nil, errors.Wrap(err, "failed to get kcp user context")
+		return jwt.WebToken{}, appcontext.KCPContext{}, errors.Wrap(err, "failed to get kcp user context")
 	}
 	a.log.Debug().Str("context", fmt.Sprintf("%+v", kctx)).Msg("Retrieved kcp context")
 
-	fieldCtx := graphql.GetFieldContext(ctx)
-	rctx, err := extractResourceContextFromArguments(fieldCtx.Args)
-	if err != nil { // coverage-ignore
+	return token, kctx, nil
+}
+
+// authorize checks the permission of the calling user on the resource and returns the context
+// enriched with the cluster ID of the resource and pinned to the authorization model the permission
+// was checked with. Public reads are not checked, so their context is not pinned.
+func (a AuthorizedDirective) authorize(ctx context.Context, token jwt.WebToken, kctx appcontext.KCPContext, rctx *graph.ResourceContext, permission string) (context.Context, error) {
+	if a.deniedResources[groupResource(rctx)] {
+		return nil, errUnauthorized
+	}
+
+	ctx, ai, err := a.resolveResource(ctx, kctx, rctx)
+	if err != nil {
 		return nil, err
 	}
-	if rctx == nil {
-		return nil, gqlerror.Errorf("resource context is nil")
+
+	// Public resources must still exist and belong to the organization of the caller
+	if a.isPublicRead(rctx, permission) {
+		a.log.Debug().Str("groupResource", groupResource(rctx)).Str("permission", permission).Msg("Skipping permission check for public resource")
+		return ctx, nil
 	}
+
+	allowed, modelID, err := a.testIfAllowed(ctx, ai, rctx, permission, token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to test if action is allowed")
+	}
+	if !allowed {
+		return nil, errUnauthorized
+	}
+
+	// The rest of the request is evaluated against the model the permission was checked with,
+	// even if a new model is published meanwhile
+	return appcontext.SetAuthorizationModelId(ctx, modelID), nil
+}
+
+// resolveResource verifies that the resource exists and belongs to the organization of the caller.
+// It returns the account info of the resource and the context enriched with the cluster ID of the resource.
+func (a AuthorizedDirective) resolveResource(ctx context.Context, kctx appcontext.KCPContext, rctx *graph.ResourceContext) (context.Context, *accountsv1alpha1.AccountInfo, error) {
 	a.log.Debug().
 		Str("group", rctx.Group).
 		Str("kind", rctx.Kind).
@@ -92,11 +339,11 @@ func (a AuthorizedDirective) Authorized(ctx context.Context, _ any, next graphql
 	}
 	ai, err := a.air.Get(ctx, path)
 	if err != nil { // coverage-ignore
-		return nil, errors.Wrap(err, "failed to get account info from kcp context")
+		return nil, nil, errors.Wrap(err, "failed to get account info from kcp context")
 	}
 
 	if ai.Spec.Organization.Name != kctx.OrganizationName {
-		return nil, gqlerror.Errorf("unauthorized")
+		return nil, nil, errUnauthorized
 	}
 
 	// The clusterID will be set to the cluster where the resource is located.
@@ -111,76 +358,157 @@ func (a AuthorizedDirective) Authorized(ctx context.Context, _ any, next graphql
 	// Test if resource exists
 	wsClient, err := a.wcClient.New(ctx, rctx.AccountPath)
 	if err != nil { // coverage-ignore
-		return nil, errors.Wrap(err, "failed to get workspace client")
+		return nil, nil, errors.Wrap(err, "failed to get workspace client")
 	}
 	exists, err := a.testIfResourceExists(ctx, rctx, wsClient)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to test if resource exists")
+		return nil, nil, errors.Wrap(err, "failed to test if resource exists")
 	}
 	if !exists {
-		return nil, gqlerror.Errorf("resource does not exist")
+		return nil, nil, errResourceNotExists
 	}
 
-	allowed, err := a.testIfAllowed(ctx, ai, rctx, permission, token)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to test if action is allowed")
-	}
-	if !allowed {
-		return nil, gqlerror.Errorf("unauthorized")
-	}
-
-	return next(ctx)
+	return ctx, ai, nil
 }
 
-func (a AuthorizedDirective) testIfAllowed(ctx context.Context, ai *accountsv1alpha1.AccountInfo, rctx *graph.ResourceContext, permission string, token jwt.WebToken) (bool, error) {
+// testIfAllowed checks the permission of the user on the resource. It returns the ID of the
+// authorization model the permission was checked with.
+func (a AuthorizedDirective) testIfAllowed(ctx context.Context, ai *accountsv1alpha1.AccountInfo, rctx *graph.ResourceContext, permission string, token jwt.WebToken) (bool, string, error) {
 	start := time.Now()
 	defer func() {
 		metrics.AuthorizationDuration.WithLabelValues(permission).Observe(time.Since(start).Seconds())
 	}()
 
 	ct := tuples.GenerateContextualTuples(rctx, ai)
+	object := resourceObject(ai, rctx)
 
-	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+	storeID, err := a.resolveStore(ctx, ai)
+	if err != nil {
+		return false, "", err
+	}
 
-	clusterId := ai.Spec.Account.GeneratedClusterId
-	if rctx.Group == "core.platform-mesh.io" && rctx.Kind == "Account" {
-		clusterId = ai.Spec.Account.OriginClusterId
+	var user, checkedModelID string
+	res, err := store.WithModelRetry(ctx, a.helper, a.fga, ai.Spec.Organization.Name, func(modelID string) (*openfgav1.CheckResponse, error) {
+		subject, err := a.callerSubject(ctx, storeID, modelID, token)
+		if err != nil {
+			return nil, err
+		}
+		user = subject
+		checkedModelID = modelID
+		return a.fga.Check(ctx, &openfgav1.CheckRequest{
+			ContextualTuples:     ct,
+			StoreId:              storeID,
+			AuthorizationModelId: modelID,
+			TupleKey: &openfgav1.CheckRequestTupleKey{
+				Object:   object,
+				Relation: permission,
+				User:     user,
+			},
+		})
+	})
+	if err != nil {
+		metrics.AuthorizationChecks.WithLabelValues("error").Inc()
+		return false, "", errors.Wrap(err, "failed to check permission with openfga")
 	}
 
-	object := fmt.Sprintf("%s:%s/%s", fgaTypeName, clusterId, rctx.Resource.Name)
-	if rctx.Resource.Namespace != nil {
-		object = fmt.Sprintf("%s:%s/%s/%s", fgaTypeName, clusterId, *rctx.Resource.Namespace, rctx.Resource.Name)
+	if res.Allowed {
+		metrics.AuthorizationChecks.WithLabelValues("allowed").Inc()
+	} else {
+		metrics.AuthorizationChecks.WithLabelValues("denied").Inc()
+		// Only the checked tuple is logged, never the token itself
+		a.log.Info().
+			Str("user", sanitizeUser(user)).
+			Str("relation", permission).
+			Str("object", object).
+			Str("storeId", storeID).
+			Str("organization", ai.Spec.Organization.Name).
+			Msg("Authorization denied")
 	}
 
-	user := fmt.Sprintf("user:%s", token.Mail) // TODO: what happens if mail is not uid?
-	storeID, err := a.helper.GetStoreID(ctx, a.fga, ai.Spec.Organization.Name)
+	return res.Allowed, checkedModelID, nil
+}
+
+// testPermissions checks all permissions of the user on the resource with a single BatchCheck request
+func (a AuthorizedDirective) testPermissions(ctx context.Context, ai *accountsv1alpha1.AccountInfo, rctx *graph.ResourceContext, permissions []string, token jwt.WebToken) (map[string]bool, error) {
+	ct := tuples.GenerateContextualTuples(rctx, ai)
+	object := resourceObject(ai, rctx)
+	storeID, err := a.resolveStore(ctx, ai)
 	if err != nil {
-		return false, errors.Wrap(err, "failed to get store ID for organization %s", ai.Spec.Organization.Name)
+		return nil, err
 	}
 
-	req := openfgav1.CheckRequest{
-		ContextualTuples: ct,
-		StoreId:          storeID,
-		TupleKey: &openfgav1.CheckRequestTupleKey{
-			Object:   object,
-			Relation: permission,
-			User:     user,
-		},
-	}
+	res, err := store.WithModelRetry(ctx, a.helper, a.fga, ai.Spec.Organization.Name, func(modelID string) (*openfgav1.BatchCheckResponse, error) {
+		user, err := a.callerSubject(ctx, storeID, modelID, token)
+		if err != nil {
+			return nil, err
+		}
+
+		// Correlation IDs are restricted to alphanumerics and dashes, so the index identifies the permission
+		checks := make([]*openfgav1.BatchCheckItem, len(permissions))
+		for i, permission := range permissions {
+			checks[i] = &openfgav1.BatchCheckItem{
+				TupleKey: &openfgav1.CheckRequestTupleKey{
+					Object:   object,
+					Relation: permission,
+					User:     user,
+				},
+				ContextualTuples: ct,
+				CorrelationId:    strconv.Itoa(i),
+			}
+		}
 
-	res, err := a.fga.Check(ctx, &req)
+		return a.fga.BatchCheck(ctx, &openfgav1.BatchCheckRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: modelID,
+			Checks:               checks,
+		})
+	})
 	if err != nil {
 		metrics.AuthorizationChecks.WithLabelValues("error").Inc()
-		return false, errors.Wrap(err, "failed to check permission with openfga")
+		return nil, errors.Wrap(err, "failed to batch check permissions with openfga")
 	}
 
-	if res.Allowed {
-		metrics.AuthorizationChecks.WithLabelValues("allowed").Inc()
-	} else {
-		metrics.AuthorizationChecks.WithLabelValues("denied").Inc()
+	allowed := make(map[string]bool, len(permissions))
+	for i, permission := range permissions {
+		result, ok := res.Result[strconv.Itoa(i)]
+		if !ok {
+			return nil, errors.New("missing batch check result for permission %s", permission)
+		}
+		if checkErr := result.GetError(); checkErr != nil {
+			metrics.AuthorizationChecks.WithLabelValues("error").Inc()
+			return nil, errors.New("failed to check permission %s with openfga: %s", permission, checkErr.GetMessage())
+		}
+
+		allowed[permission] = result.GetAllowed()
+		if allowed[permission] {
+			metrics.AuthorizationChecks.WithLabelValues("allowed").Inc()
+		} else {
+			metrics.AuthorizationChecks.WithLabelValues("denied").Inc()
+		}
+	}
+	return allowed, nil
+}
+
+// resolveStore returns the store ID of the organization of the resource
+func (a AuthorizedDirective) resolveStore(ctx context.Context, ai *accountsv1alpha1.AccountInfo) (string, error) {
+	storeID, err := a.helper.GetStoreID(ctx, a.fga, ai.Spec.Organization.Name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get store ID for organization %s", ai.Spec.Organization.Name)
+	}
+	return storeID, nil
+}
+
+// resourceObject returns the FGA object of the resource. Accounts are stored in
+// the cluster of their parent, so the origin cluster ID is used for them.
+func resourceObject(ai *accountsv1alpha1.AccountInfo, rctx *graph.ResourceContext) string {
+	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+
+	clusterId := ai.Spec.Account.GeneratedClusterId
+	if rctx.Group == "core.platform-mesh.io" && rctx.Kind == "Account" {
+		clusterId = ai.Spec.Account.OriginClusterId
 	}
 
-	return res.Allowed, nil
+	return tuples.ResourceObject(fgaTypeName, clusterId, rctx.Resource.Namespace, rctx.Resource.Name)
 }
 
 func (a AuthorizedDirective) testIfResourceExists(ctx context.Context, rctx *graph.ResourceContext, wsClient client.Client) (bool, error) {
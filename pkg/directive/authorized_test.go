@@ -1,7 +1,9 @@
 package directive
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -15,6 +17,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -24,6 +29,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	accountinfomocks "github.com/platform-mesh/iam-service/pkg/accountinfo/mocks"
+	"github.com/platform-mesh/iam-service/pkg/config"
 	appcontext "github.com/platform-mesh/iam-service/pkg/context"
 	fgamocks "github.com/platform-mesh/iam-service/pkg/fga/mocks"
 	"github.com/platform-mesh/iam-service/pkg/graph"
@@ -122,6 +128,9 @@ func TestAuthorized_HappyPath(t *testing.T) {
 	checkResponse := &openfgav1.CheckResponse{
 		Allowed: true,
 	}
+	fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}, nil)
 	fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(checkResponse, nil)
 
 	ai := createTestAccountInfo()
@@ -168,6 +177,10 @@ func TestAuthorized_HappyPath(t *testing.T) {
 		clusterId, err := appcontext.GetClusterId(ctx)
 		assert.NoError(t, err)
 		assert.NotEmpty(t, clusterId)
+		// The resolver uses the model the permission was checked with
+		modelID, pinned := appcontext.GetAuthorizationModelId(ctx)
+		assert.True(t, pinned)
+		assert.Equal(t, "model-123", modelID)
 		return "success", nil
 	}
 
@@ -412,6 +425,9 @@ func TestAuthorized_NotAllowed(t *testing.T) {
 	checkResponse := &openfgav1.CheckResponse{
 		Allowed: false, // Not allowed
 	}
+	fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}, nil)
 	fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(checkResponse, nil)
 
 	ai := createTestAccountInfo()
@@ -459,6 +475,428 @@ func TestAuthorized_NotAllowed(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Contains(t, err.Error(), "unauthorized")
+
+	// gqlgen sets the path on the returned error, so every call must get its own error
+	_, otherErr := directive.Authorized(ctx, nil, next, "read")
+	var gqlErr, otherGqlErr *gqlerror.Error
+	require.ErrorAs(t, err, &gqlErr)
+	require.ErrorAs(t, otherErr, &otherGqlErr)
+	assert.NotSame(t, gqlErr, otherGqlErr)
+}
+
+func TestAuthorized_ResourceRules(t *testing.T) {
+	rules := config.AuthorizationConfig{
+		PublicResources: []string{"core.platform-mesh.io/AccountInfo"},
+		DeniedResources: []string{"example.io/Secret"},
+	}
+
+	tests := []struct {
+		name          string
+		group         string
+		kind          string
+		resourceName  string
+		permission    string
+		setupMocks    func(*fgamocks.OpenFGAServiceClient, *accountinfomocks.Retriever)
+		expectedError string
+	}{
+		{
+			name:         "public resource is read without permission check",
+			group:        "core.platform-mesh.io",
+			kind:         "AccountInfo",
+			resourceName: "account",
+			permission:   "get_iam_users",
+			setupMocks: func(_ *fgamocks.OpenFGAServiceClient, air *accountinfomocks.Retriever) {
+				// Nothing is checked against a model, so FGA is not asked and no model is pinned
+				air.EXPECT().Get(mock.Anything, "root:orgs:test").Return(createTestAccountInfo(), nil)
+			},
+		},
+		{
+			name:         "public resource must exist",
+			group:        "core.platform-mesh.io",
+			kind:         "AccountInfo",
+			resourceName: "nonexistent-resource",
+			permission:   "get_iam_users",
+			setupMocks: func(_ *fgamocks.OpenFGAServiceClient, air *accountinfomocks.Retriever) {
+				air.EXPECT().Get(mock.Anything, "root:orgs:test").Return(createTestAccountInfo(), nil)
+			},
+			expectedError: "resource does not exist",
+		},
+		{
+			name:         "write on public resource is checked",
+			group:        "core.platform-mesh.io",
+			kind:         "AccountInfo",
+			resourceName: "account",
+			permission:   "manage_iam_roles",
+			setupMocks: func(fgaClient *fgamocks.OpenFGAServiceClient, air *accountinfomocks.Retriever) {
+				air.EXPECT().Get(mock.Anything, "root:orgs:test").Return(createTestAccountInfo(), nil)
+				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+					Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+				}, nil)
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+					AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+				}, nil)
+				fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: false}, nil)
+			},
+			expectedError: "unauthorized",
+		},
+		{
+			name:          "denied resource is rejected without lookup",
+			group:         "example.io",
+			kind:          "Secret",
+			resourceName:  "account",
+			permission:    "get_iam_users",
+			setupMocks:    func(_ *fgamocks.OpenFGAServiceClient, _ *accountinfomocks.Retriever) {},
+			expectedError: "unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, log := setupTestContext()
+
+			fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+			accountInfoRetriever := accountinfomocks.NewRetriever(t)
+			tt.setupMocks(fgaClient, accountInfoRetriever)
+
+			wsClient := &mockWSClient{client: setupFakeClient(t, createTestAccountInfo())}
+			directive := NewAuthorizedDirective(fgaClient, accountInfoRetriever, 5*time.Minute, wsClient, log)
+			directive.SetResourceRules(rules)
+
+			ctx = context.WithValue(ctx, keys.WebTokenCtxKey, createTestWebToken())
+			ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+				IDMTenant:        "test-tenant",
+				OrganizationName: "test-org",
+			})
+			ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+				Args: map[string]any{
+					"context": map[string]any{
+						"group":       tt.group,
+						"kind":        tt.kind,
+						"accountPath": "root:orgs:test",
+						"resource": map[string]any{
+							"name": tt.resourceName,
+						},
+					},
+				},
+			})
+
+			nextCalled := false
+			next := func(ctx context.Context) (any, error) {
+				nextCalled = true
+				return "success", nil
+			}
+
+			result, err := directive.Authorized(ctx, nil, next, tt.permission)
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				assert.False(t, nextCalled)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "success", result)
+				assert.True(t, nextCalled)
+			}
+		})
+	}
+}
+
+func TestCanI(t *testing.T) {
+	listStoresResponse := &openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}
+	readModelsResponse := &openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}
+
+	tests := []struct {
+		name           string
+		resourceName   string
+		organization   string
+		setupMocks     func(*fgamocks.OpenFGAServiceClient)
+		expectedResult bool
+		expectedError  string
+	}{
+		{
+			name:         "allowed",
+			resourceName: "account",
+			organization: "test-org",
+			setupMocks: func(fgaClient *fgamocks.OpenFGAServiceClient) {
+				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModelsResponse, nil)
+				fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+			},
+			expectedResult: true,
+		},
+		{
+			name:         "denied",
+			resourceName: "account",
+			organization: "test-org",
+			setupMocks: func(fgaClient *fgamocks.OpenFGAServiceClient) {
+				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModelsResponse, nil)
+				fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: false}, nil)
+			},
+			expectedResult: false,
+		},
+		{
+			name:           "resource does not exist is reported as denied",
+			resourceName:   "nonexistent-resource",
+			organization:   "test-org",
+			setupMocks:     func(fgaClient *fgamocks.OpenFGAServiceClient) {},
+			expectedResult: false,
+		},
+		{
+			name:           "other organization is reported as denied",
+			resourceName:   "account",
+			organization:   "other-org",
+			setupMocks:     func(fgaClient *fgamocks.OpenFGAServiceClient) {},
+			expectedResult: false,
+		},
+		{
+			name:         "store resolution error",
+			resourceName: "account",
+			organization: "test-org",
+			setupMocks: func(fgaClient *fgamocks.OpenFGAServiceClient) {
+				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(nil, fmt.Errorf("store not found"))
+			},
+			expectedError: "failed to get store ID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, log := setupTestContext()
+
+			fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+			accountInfoRetriever := accountinfomocks.NewRetriever(t)
+			tt.setupMocks(fgaClient)
+
+			ai := createTestAccountInfo()
+			accountInfoRetriever.EXPECT().Get(mock.Anything, "root:orgs:test").Return(ai, nil)
+
+			wsClient := &mockWSClient{client: setupFakeClient(t, ai)}
+			directive := NewAuthorizedDirective(fgaClient, accountInfoRetriever, 5*time.Minute, wsClient, log)
+
+			ctx = context.WithValue(ctx, keys.WebTokenCtxKey, createTestWebToken())
+			ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+				IDMTenant:        "test-tenant",
+				OrganizationName: tt.organization,
+			})
+
+			rctx := graph.ResourceContext{
+				Group:       "core.platform-mesh.io",
+				Kind:        "AccountInfo",
+				AccountPath: "root:orgs:test",
+				Resource:    &graph.Resource{Name: tt.resourceName},
+			}
+
+			result, err := directive.CanI(ctx, rctx, "read")
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.False(t, result)
+				assert.Contains(t, err.Error(), tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResult, result)
+			}
+		})
+	}
+}
+
+func TestCanI_MissingWebToken(t *testing.T) {
+	ctx, log := setupTestContext()
+	directive := NewAuthorizedDirective(fgamocks.NewOpenFGAServiceClient(t), accountinfomocks.NewRetriever(t), 5*time.Minute, &mockWSClient{}, log)
+
+	result, err := directive.CanI(ctx, *createTestResourceContext(), "read")
+
+	assert.Error(t, err)
+	assert.False(t, result)
+	assert.Contains(t, err.Error(), "failed to get web token")
+}
+
+func TestCheckPermissions(t *testing.T) {
+	listStoresResponse := &openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}
+	readModelsResponse := &openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}
+	allowed := func(allowed bool) *openfgav1.BatchCheckSingleResult {
+		return &openfgav1.BatchCheckSingleResult{CheckResult: &openfgav1.BatchCheckSingleResult_Allowed{Allowed: allowed}}
+	}
+
+	tests := []struct {
+		name           string
+		resourceName   string
+		organization   string
+		setupMocks     func(*fgamocks.OpenFGAServiceClient)
+		expectedResult map[string]bool
+		expectedError  string
+	}{
+		{
+			name:         "mixed results",
+			resourceName: "account",
+			organization: "test-org",
+			setupMocks: func(fgaClient *fgamocks.OpenFGAServiceClient) {
+				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModelsResponse, nil)
+				fgaClient.EXPECT().BatchCheck(mock.Anything, mock.MatchedBy(func(req *openfgav1.BatchCheckRequest) bool {
+					if req.StoreId != "store-123" || req.AuthorizationModelId != "model-123" || len(req.Checks) != 3 {
+						return false
+					}
+					for _, check := range req.Checks {
+						if check.TupleKey.User != "user:test@example.com" || check.TupleKey.Object != "core_platform-mesh_io_accountinfo:generated-cluster-456/account" {
+							return false
+						}
+					}
+					return true
+				})).Return(&openfgav1.BatchCheckResponse{Result: map[string]*openfgav1.BatchCheckSingleResult{
+					// Permissions are checked in sorted order: delete, read, write
+					"0": allowed(false),
+					"1": allowed(true),
+					"2": allowed(true),
+				}}, nil)
+			},
+			expectedResult: map[string]bool{"read": true, "write": true, "delete": false},
+		},
+		{
+			name:           "resource does not exist is reported as denied",
+			resourceName:   "nonexistent-resource",
+			organization:   "test-org",
+			setupMocks:     func(fgaClient *fgamocks.OpenFGAServiceClient) {},
+			expectedResult: map[string]bool{"read": false, "write": false, "delete": false},
+		},
+		{
+			name:           "other organization is reported as denied",
+			resourceName:   "account",
+			organization:   "other-org",
+			setupMocks:     func(fgaClient *fgamocks.OpenFGAServiceClient) {},
+			expectedResult: map[string]bool{"read": false, "write": false, "delete": false},
+		},
+		{
+			name:         "single check error",
+			resourceName: "account",
+			organization: "test-org",
+			setupMocks: func(fgaClient *fgamocks.OpenFGAServiceClient) {
+				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModelsResponse, nil)
+				fgaClient.EXPECT().BatchCheck(mock.Anything, mock.Anything).Return(&openfgav1.BatchCheckResponse{Result: map[string]*openfgav1.BatchCheckSingleResult{
+					"0": allowed(false),
+					"1": {CheckResult: &openfgav1.BatchCheckSingleResult_Error{Error: &openfgav1.CheckError{Message: "relation not found"}}},
+					"2": allowed(true),
+				}}, nil)
+			},
+			expectedError: "failed to check permission read with openfga: relation not found",
+		},
+		{
+			name:         "missing result",
+			resourceName: "account",
+			organization: "test-org",
+			setupMocks: func(fgaClient *fgamocks.OpenFGAServiceClient) {
+				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModelsResponse, nil)
+				fgaClient.EXPECT().BatchCheck(mock.Anything, mock.Anything).Return(&openfgav1.BatchCheckResponse{}, nil)
+			},
+			expectedError: "missing batch check result for permission delete",
+		},
+		{
+			name:         "batch check error",
+			resourceName: "account",
+			organization: "test-org",
+			setupMocks: func(fgaClient *fgamocks.OpenFGAServiceClient) {
+				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(readModelsResponse, nil)
+				fgaClient.EXPECT().BatchCheck(mock.Anything, mock.Anything).Return(nil, fmt.Errorf("unavailable"))
+			},
+			expectedError: "failed to batch check permissions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, log := setupTestContext()
+
+			fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+			accountInfoRetriever := accountinfomocks.NewRetriever(t)
+			tt.setupMocks(fgaClient)
+
+			ai := createTestAccountInfo()
+			accountInfoRetriever.EXPECT().Get(mock.Anything, "root:orgs:test").Return(ai, nil)
+
+			wsClient := &mockWSClient{client: setupFakeClient(t, ai)}
+			directive := NewAuthorizedDirective(fgaClient, accountInfoRetriever, 5*time.Minute, wsClient, log)
+
+			ctx = context.WithValue(ctx, keys.WebTokenCtxKey, createTestWebToken())
+			ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+				IDMTenant:        "test-tenant",
+				OrganizationName: tt.organization,
+			})
+
+			rctx := graph.ResourceContext{
+				Group:       "core.platform-mesh.io",
+				Kind:        "AccountInfo",
+				AccountPath: "root:orgs:test",
+				Resource:    &graph.Resource{Name: tt.resourceName},
+			}
+
+			result, err := directive.CheckPermissions(ctx, rctx, []string{"read", "write", "delete", "read"})
+
+			if tt.expectedError != "" {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+				assert.Contains(t, err.Error(), tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResult, result)
+			}
+		})
+	}
+}
+
+func TestCheckPermissions_NoPermissions(t *testing.T) {
+	ctx, log := setupTestContext()
+	directive := NewAuthorizedDirective(fgamocks.NewOpenFGAServiceClient(t), accountinfomocks.NewRetriever(t), 5*time.Minute, &mockWSClient{}, log)
+	ctx = context.WithValue(ctx, keys.WebTokenCtxKey, createTestWebToken())
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{OrganizationName: "test-org"})
+
+	result, err := directive.CheckPermissions(ctx, *createTestResourceContext(), nil)
+
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestCheckPermissions_ResourceRules(t *testing.T) {
+	ctx, log := setupTestContext()
+
+	accountInfoRetriever := accountinfomocks.NewRetriever(t)
+	ai := createTestAccountInfo()
+	accountInfoRetriever.EXPECT().Get(mock.Anything, "root:orgs:test").Return(ai, nil)
+
+	directive := NewAuthorizedDirective(fgamocks.NewOpenFGAServiceClient(t), accountInfoRetriever, 5*time.Minute, &mockWSClient{client: setupFakeClient(t, ai)}, log)
+	directive.SetResourceRules(config.AuthorizationConfig{
+		PublicResources: []string{"core.platform-mesh.io/AccountInfo"},
+		DeniedResources: []string{"example.io/Secret"},
+	})
+	ctx = context.WithValue(ctx, keys.WebTokenCtxKey, createTestWebToken())
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{IDMTenant: "test-tenant", OrganizationName: "test-org"})
+
+	rctx := graph.ResourceContext{
+		Group:       "core.platform-mesh.io",
+		Kind:        "AccountInfo",
+		AccountPath: "root:orgs:test",
+		Resource:    &graph.Resource{Name: "account"},
+	}
+	result, err := directive.CheckPermissions(ctx, rctx, []string{"get_iam_users", "get_iam_roles"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"get_iam_users": true, "get_iam_roles": true}, result)
+
+	rctx.Group, rctx.Kind = "example.io", "Secret"
+	result, err = directive.CheckPermissions(ctx, rctx, []string{"get_iam_users"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"get_iam_users": false}, result)
 }
 
 func TestExtractResourceContextFromArguments(t *testing.T) {
@@ -609,8 +1047,12 @@ func TestTestIfAllowed(t *testing.T) {
 				}
 				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
 
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+					AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+				}, nil)
 				fgaClient.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
 					return req.StoreId == "store-123" &&
+						req.AuthorizationModelId == "model-123" &&
 						req.TupleKey.Relation == "read" &&
 						req.TupleKey.User == "user:test@example.com" &&
 						req.TupleKey.Object == "apps_deployment:generated-cluster-456/test-namespace/test-deployment"
@@ -635,6 +1077,9 @@ func TestTestIfAllowed(t *testing.T) {
 				}
 				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
 
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+					AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+				}, nil)
 				fgaClient.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
 					return req.StoreId == "store-123" &&
 						req.TupleKey.Relation == "read" &&
@@ -669,6 +1114,9 @@ func TestTestIfAllowed(t *testing.T) {
 				}
 				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
 
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+					AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+				}, nil)
 				fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: false}, nil)
 			},
 			accountInfo:    createTestAccountInfo(),
@@ -690,6 +1138,9 @@ func TestTestIfAllowed(t *testing.T) {
 				}
 				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
 
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+					AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+				}, nil)
 				fgaClient.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
 					return req.StoreId == "store-123" &&
 						req.TupleKey.Relation == "read" &&
@@ -735,6 +1186,9 @@ func TestTestIfAllowed(t *testing.T) {
 				}
 				fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
 
+				fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+					AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+				}, nil)
 				fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(nil, fmt.Errorf("FGA check failed"))
 			},
 			accountInfo:   createTestAccountInfo(),
@@ -762,7 +1216,7 @@ func TestTestIfAllowed(t *testing.T) {
 			directive := NewAuthorizedDirective(fgaClient, accountInfoRetriever, 5*time.Minute, wsClient, log)
 
 			// Execute test
-			result, err := directive.testIfAllowed(ctx, tt.accountInfo, tt.resourceCtx, tt.permission, tt.token)
+			result, _, err := directive.testIfAllowed(ctx, tt.accountInfo, tt.resourceCtx, tt.permission, tt.token)
 
 			// Verify results
 			if tt.expectedError != "" {
@@ -777,6 +1231,256 @@ func TestTestIfAllowed(t *testing.T) {
 	}
 }
 
+func TestTestIfAllowed_PinnedAuthorizationModel(t *testing.T) {
+	ctx, log := setupTestContext()
+	ctx = appcontext.SetAuthorizationModelId(ctx, "pinned-model")
+
+	fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+	fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	// The pinned model is used as is, the latest model is not looked up
+	fgaClient.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+		return req.AuthorizationModelId == "pinned-model"
+	})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+
+	directive := NewAuthorizedDirective(fgaClient, accountinfomocks.NewRetriever(t), 5*time.Minute, &mockWSClient{client: setupFakeClient(t)}, log)
+
+	allowed, modelID, err := directive.testIfAllowed(ctx, createTestAccountInfo(), createTestResourceContext(), "read", createTestWebToken())
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, "pinned-model", modelID)
+}
+
+func TestTestIfAllowed_StaleAuthorizationModel(t *testing.T) {
+	ctx, log := setupTestContext()
+	modelNotFound := status.Error(codes.Code(openfgav1.ErrorCode_authorization_model_not_found), "authorization model not found")
+
+	fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+	fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil).Once()
+	fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "old-model"}},
+	}, nil).Once()
+	fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "new-model"}},
+	}, nil).Once()
+	fgaClient.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+		return req.AuthorizationModelId == "old-model"
+	})).Return(nil, modelNotFound).Once()
+	fgaClient.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+		return req.AuthorizationModelId == "new-model"
+	})).Return(&openfgav1.CheckResponse{Allowed: true}, nil).Twice()
+
+	directive := NewAuthorizedDirective(fgaClient, accountinfomocks.NewRetriever(t), 5*time.Minute, &mockWSClient{client: setupFakeClient(t)}, log)
+
+	// The model the check succeeded with is returned, not the stale one
+	allowed, modelID, err := directive.testIfAllowed(ctx, createTestAccountInfo(), createTestResourceContext(), "read", createTestWebToken())
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, "new-model", modelID)
+
+	// The re-resolved model is cached for subsequent checks
+	allowed, modelID, err = directive.testIfAllowed(ctx, createTestAccountInfo(), createTestResourceContext(), "read", createTestWebToken())
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, "new-model", modelID)
+}
+
+func TestTestPermissions_StaleAuthorizationModel(t *testing.T) {
+	ctx, log := setupTestContext()
+
+	fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+	fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil).Once()
+	fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "old-model"}},
+	}, nil).Once()
+	fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "new-model"}},
+	}, nil).Once()
+	fgaClient.EXPECT().BatchCheck(mock.Anything, mock.MatchedBy(func(req *openfgav1.BatchCheckRequest) bool {
+		return req.AuthorizationModelId == "old-model"
+	})).Return(nil, status.Error(codes.Code(openfgav1.ErrorCode_authorization_model_not_found), "authorization model not found")).Once()
+	fgaClient.EXPECT().BatchCheck(mock.Anything, mock.MatchedBy(func(req *openfgav1.BatchCheckRequest) bool {
+		return req.AuthorizationModelId == "new-model"
+	})).Return(&openfgav1.BatchCheckResponse{Result: map[string]*openfgav1.BatchCheckSingleResult{
+		"0": {CheckResult: &openfgav1.BatchCheckSingleResult_Allowed{Allowed: true}},
+	}}, nil).Once()
+
+	directive := NewAuthorizedDirective(fgaClient, accountinfomocks.NewRetriever(t), 5*time.Minute, &mockWSClient{client: setupFakeClient(t)}, log)
+
+	allowed, err := directive.testPermissions(ctx, createTestAccountInfo(), createTestResourceContext(), []string{"read"}, createTestWebToken())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"read": true}, allowed)
+}
+
+func TestTestIfAllowed_StalePinnedAuthorizationModel(t *testing.T) {
+	ctx, log := setupTestContext()
+	ctx = appcontext.SetAuthorizationModelId(ctx, "pinned-model")
+
+	fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+	fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	// A pinned model is not replaced, so the check is not retried
+	fgaClient.EXPECT().Check(mock.Anything, mock.Anything).
+		Return(nil, status.Error(codes.Code(openfgav1.ErrorCode_authorization_model_not_found), "authorization model not found")).Once()
+
+	directive := NewAuthorizedDirective(fgaClient, accountinfomocks.NewRetriever(t), 5*time.Minute, &mockWSClient{client: setupFakeClient(t)}, log)
+
+	allowed, _, err := directive.testIfAllowed(ctx, createTestAccountInfo(), createTestResourceContext(), "read", createTestWebToken())
+	assert.Error(t, err)
+	assert.False(t, allowed)
+	assert.Contains(t, err.Error(), "authorization model pinned-model not found")
+}
+
+func TestTestIfAllowed_ModelLookupError(t *testing.T) {
+	ctx, log := setupTestContext()
+
+	fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+	fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(nil, fmt.Errorf("unavailable"))
+
+	directive := NewAuthorizedDirective(fgaClient, accountinfomocks.NewRetriever(t), 5*time.Minute, &mockWSClient{client: setupFakeClient(t)}, log)
+
+	allowed, _, err := directive.testIfAllowed(ctx, createTestAccountInfo(), createTestResourceContext(), "read", createTestWebToken())
+	assert.Error(t, err)
+	assert.False(t, allowed)
+	assert.Contains(t, err.Error(), "failed to get authorization model ID")
+}
+
+func TestTestIfAllowed_LogsDenial(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	log, err := logger.New(logger.Config{Level: "info", Output: &buf})
+	require.NoError(t, err)
+
+	fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+	fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}, nil)
+	fgaClient.EXPECT().Check(mock.Anything, mock.Anything).Return(&openfgav1.CheckResponse{Allowed: false}, nil)
+
+	directive := NewAuthorizedDirective(fgaClient, accountinfomocks.NewRetriever(t), 5*time.Minute, &mockWSClient{client: setupFakeClient(t)}, log)
+
+	token := createTestWebToken()
+	token.Subject = "secret-subject"
+	allowed, _, err := directive.testIfAllowed(ctx, createTestAccountInfo(), createTestResourceContext(), "write", token)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "Authorization denied", entry["message"])
+	assert.Equal(t, "user:tes***@example.com", entry["user"])
+	assert.Equal(t, "write", entry["relation"])
+	assert.Equal(t, "apps_deployment:generated-cluster-456/test-namespace/test-deployment", entry["object"])
+	assert.Equal(t, "store-123", entry["storeId"])
+	assert.Equal(t, "test-org", entry["organization"])
+
+	// Neither the full mail nor any token data must end up in the log
+	assert.NotContains(t, buf.String(), "test@example.com")
+	assert.NotContains(t, buf.String(), "secret-subject")
+}
+
+// newServiceAccountDirective returns a directive whose organization model defines the given types
+func newServiceAccountDirective(t *testing.T, types ...string) (*AuthorizedDirective, *fgamocks.OpenFGAServiceClient) {
+	fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+	fgaClient.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil).Maybe()
+	fgaClient.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}, nil).Maybe()
+
+	typeDefinitions := make([]*openfgav1.TypeDefinition, len(types))
+	for i, typeName := range types {
+		typeDefinitions[i] = &openfgav1.TypeDefinition{Type: typeName}
+	}
+	// The answer is cached, so the model is read only once
+	fgaClient.EXPECT().ReadAuthorizationModel(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadAuthorizationModelRequest) bool {
+		return req.StoreId == "store-123" && req.Id == "model-123"
+	})).Return(&openfgav1.ReadAuthorizationModelResponse{
+		AuthorizationModel: &openfgav1.AuthorizationModel{Id: "model-123", TypeDefinitions: typeDefinitions},
+	}, nil).Maybe().Once()
+
+	log, _ := logger.New(logger.DefaultConfig())
+	directive := NewAuthorizedDirective(fgaClient, accountinfomocks.NewRetriever(t), 5*time.Minute, &mockWSClient{client: setupFakeClient(t)}, log)
+	return directive, fgaClient
+}
+
+func TestTestIfAllowed_ServiceAccount(t *testing.T) {
+	directive, fgaClient := newServiceAccountDirective(t, "user", "serviceaccount")
+	fgaClient.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+		return req.TupleKey.User == "serviceaccount:ci-bot"
+	})).Return(&openfgav1.CheckResponse{Allowed: true}, nil)
+
+	token := jwt.WebToken{IssuerAttributes: jwt.IssuerAttributes{Subject: "ci-bot"}}
+	allowed, _, err := directive.testIfAllowed(context.Background(), createTestAccountInfo(), createTestResourceContext(), "write", token)
+
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestTestIfAllowed_ServiceAccountTypeNotInModel(t *testing.T) {
+	directive, fgaClient := newServiceAccountDirective(t, "user")
+	// Without the type in the model the caller is checked as before instead of failing the check
+	fgaClient.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+		return req.TupleKey.User == "user:"
+	})).Return(&openfgav1.CheckResponse{Allowed: false}, nil).Times(2)
+
+	token := jwt.WebToken{IssuerAttributes: jwt.IssuerAttributes{Subject: "ci-bot"}}
+	for range 2 {
+		allowed, _, err := directive.testIfAllowed(context.Background(), createTestAccountInfo(), createTestResourceContext(), "write", token)
+
+		require.NoError(t, err)
+		assert.False(t, allowed)
+	}
+}
+
+func TestCallerSubject(t *testing.T) {
+	ctx := context.Background()
+	directive, _ := newServiceAccountDirective(t, "user", "serviceaccount")
+
+	user, err := directive.callerSubject(ctx, "store-123", "model-123", jwt.WebToken{
+		IssuerAttributes: jwt.IssuerAttributes{Subject: "f3c1a2"},
+		ParsedAttributes: jwt.ParsedAttributes{Mail: "test@example.com"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user:test@example.com", user)
+
+	user, err = directive.callerSubject(ctx, "store-123", "model-123", jwt.WebToken{IssuerAttributes: jwt.IssuerAttributes{Subject: "ci-bot"}})
+	require.NoError(t, err)
+	assert.Equal(t, "serviceaccount:ci-bot", user)
+
+	_, err = directive.callerSubject(ctx, "store-123", "model-123", jwt.WebToken{})
+	assert.Error(t, err)
+}
+
+func TestCallerSubject_ModelReadError(t *testing.T) {
+	fgaClient := fgamocks.NewOpenFGAServiceClient(t)
+	fgaClient.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+	directive := NewAuthorizedDirectiveWithFactory(fgaClient, accountinfomocks.NewRetriever(t), 5*time.Minute, &mockWSClient{client: setupFakeClient(t)})
+
+	_, err := directive.callerSubject(context.Background(), "store-123", "model-123", jwt.WebToken{IssuerAttributes: jwt.IssuerAttributes{Subject: "ci-bot"}})
+
+	assert.ErrorContains(t, err, "failed to read authorization model model-123")
+}
+
+func TestSanitizeUser(t *testing.T) {
+	assert.Equal(t, "user:tes***@example.com", sanitizeUser("user:test@example.com"))
+	assert.Equal(t, "user:***", sanitizeUser("user:abc"))
+	assert.Equal(t, "serviceaccount:***", sanitizeUser("serviceaccount:bot"))
+}
+
 func TestTestIfResourceExists(t *testing.T) {
 	tests := []struct {
 		name           string
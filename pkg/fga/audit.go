@@ -0,0 +1,156 @@
+package fga
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/fga/util"
+	"github.com/platform-mesh/golang-commons/logger"
+	"go.opentelemetry.io/otel"
+
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
+	"github.com/platform-mesh/iam-service/pkg/fga/tuples"
+	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/platform-mesh/iam-service/pkg/redact"
+	"github.com/platform-mesh/iam-service/pkg/roles"
+)
+
+// RoleAudit describes inconsistencies in the role assignments of a single user on a resource
+type RoleAudit struct {
+	UserID string
+	// Roles are the roles explicitly assigned to the user, highest ranked role first
+	Roles []string
+	// Duplicates are the roles assigned more than once, e.g. to the same email in different casing
+	Duplicates []string
+	// Redundant are the assigned roles already granted by another assigned role
+	Redundant []*RedundantRole
+}
+
+// RedundantRole is an assigned role the authorization model derives from another assigned role
+type RedundantRole struct {
+	Role         string
+	SupersededBy string
+}
+
+// Clean reports whether the audit found no inconsistencies
+func (a *RoleAudit) Clean() bool {
+	return len(a.Duplicates) == 0 && len(a.Redundant) == 0
+}
+
+// AuditUserRoles reports duplicate and redundant role assignments of the user on the resource in rctx.
+// A role is redundant if the authorization model grants it to everybody holding another assigned role,
+// e.g. "define member: [role#assignee] or owner" makes member redundant for owners.
+func (s *Service) AuditUserRoles(ctx context.Context, rctx graph.ResourceContext, userID string) (*RoleAudit, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	log = log.MustChildLoggerWithAttributes("group", rctx.Group, "kind", rctx.Kind, "resource", rctx.Resource.Name)
+	ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "fga.AuditUserRoles")
+	defer span.End()
+
+	clusterId, err := appcontext.GetClusterId(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster ID from context")
+	}
+
+	kctx, err := appcontext.GetKCPContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kcp user context")
+	}
+
+	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
+	}
+
+	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
+	if err != nil { // coverage-ignore
+		return nil, errors.Wrap(err, "failed to get role definitions for group resource %s/%s", rctx.Group, rctx.Kind)
+	}
+
+	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+	user := tuples.User(userID)
+	audit := &RoleAudit{UserID: userID}
+	for _, role := range roles.GetAvailableRoleIDs(roleDefinitions) {
+		assignees, err := s.readAll(ctx, storeID, &openfgav1.ReadRequestTupleKey{
+			Relation: tuples.AssigneeRelation,
+			Object:   tuples.RoleObject(fgaTypeName, clusterId, rctx.Resource.Name, role),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read assignees of role %s on resource %s", role, rctx.Resource.Name)
+		}
+
+		// FGA identifies users by email, which is compared case-insensitively
+		assignments := 0
+		for _, assignee := range assignees {
+			if strings.EqualFold(assignee.Key.User, user) {
+				assignments++
+			}
+		}
+		if assignments > 0 {
+			audit.Roles = append(audit.Roles, role)
+		}
+		if assignments > 1 {
+			audit.Duplicates = append(audit.Duplicates, role)
+		}
+	}
+
+	if len(audit.Roles) < 2 {
+		return audit, nil
+	}
+
+	typeDef, err := s.readTypeDefinition(ctx, storeID, kctx.OrganizationName, fgaTypeName)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range audit.Roles {
+		implied := impliedBy(typeDef, role)
+		for _, other := range audit.Roles {
+			if other != role && slices.Contains(implied, other) {
+				audit.Redundant = append(audit.Redundant, &RedundantRole{Role: role, SupersededBy: other})
+				break
+			}
+		}
+	}
+
+	if !audit.Clean() {
+		log.Info().Str("user", redact.Email(userID)).Strs("duplicates", audit.Duplicates).Int("redundant", len(audit.Redundant)).Msg("Found inconsistent role assignments")
+	}
+	return audit, nil
+}
+
+// impliedBy returns the relations of the type which grant the relation to all of their users,
+// following computed usersets in unions transitively
+func impliedBy(typeDef *openfgav1.TypeDefinition, relation string) []string {
+	var result []string
+	pending := []string{relation}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		for _, computed := range computedRelations(typeDef.GetRelations()[current]) {
+			if computed != relation && !slices.Contains(result, computed) {
+				result = append(result, computed)
+				pending = append(pending, computed)
+			}
+		}
+	}
+	return result
+}
+
+// computedRelations returns the relations of the same object a userset grants access to unconditionally.
+// Intersections and exclusions restrict access, so relations nested in them are ignored.
+func computedRelations(userset *openfgav1.Userset) []string {
+	switch u := userset.GetUserset().(type) {
+	case *openfgav1.Userset_ComputedUserset:
+		return []string{u.ComputedUserset.GetRelation()}
+	case *openfgav1.Userset_Union:
+		var result []string
+		for _, child := range u.Union.GetChild() {
+			result = append(result, computedRelations(child)...)
+		}
+		return result
+	default:
+		return nil
+	}
+}
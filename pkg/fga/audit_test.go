@@ -0,0 +1,122 @@
+package fga
+
+import (
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	fgamocks "github.com/platform-mesh/iam-service/pkg/fga/mocks"
+)
+
+// expectAccountModel returns a model in which owners are members as well
+func expectAccountModel(client *fgamocks.OpenFGAServiceClient) {
+	direct := &openfgav1.Userset{Userset: &openfgav1.Userset_This{This: &openfgav1.DirectUserset{}}}
+	client.EXPECT().ReadAuthorizationModel(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadAuthorizationModelRequest) bool {
+		return req.StoreId == "store-123" && req.Id == "model-123"
+	})).Return(&openfgav1.ReadAuthorizationModelResponse{
+		AuthorizationModel: &openfgav1.AuthorizationModel{
+			Id: "model-123",
+			TypeDefinitions: []*openfgav1.TypeDefinition{{
+				Type: "core_platform-mesh_io_account",
+				Relations: map[string]*openfgav1.Userset{
+					"owner": direct,
+					"member": {Userset: &openfgav1.Userset_Union{Union: &openfgav1.Usersets{Child: []*openfgav1.Userset{
+						direct,
+						{Userset: &openfgav1.Userset_ComputedUserset{ComputedUserset: &openfgav1.ObjectRelation{Relation: "owner"}}},
+					}}}},
+				},
+			}},
+		},
+	}, nil).Once()
+}
+
+func TestService_AuditUserRoles_Clean(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:other@example.com")
+	expectRoleAssignees(client, "member", "user:jane@example.com", "user:other@example.com")
+
+	audit, err := service.AuditUserRoles(ctx, rCtx, "jane@example.com")
+
+	require.NoError(t, err)
+	assert.True(t, audit.Clean())
+	assert.Equal(t, []string{"member"}, audit.Roles)
+	assert.Empty(t, audit.Duplicates)
+	assert.Empty(t, audit.Redundant)
+}
+
+func TestService_AuditUserRoles_Duplicates(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner")
+	expectRoleAssignees(client, "member", "user:jane@example.com", "user:Jane@Example.com")
+
+	audit, err := service.AuditUserRoles(ctx, rCtx, "jane@example.com")
+
+	require.NoError(t, err)
+	assert.False(t, audit.Clean())
+	assert.Equal(t, []string{"member"}, audit.Roles)
+	assert.Equal(t, []string{"member"}, audit.Duplicates)
+	assert.Empty(t, audit.Redundant)
+}
+
+func TestService_AuditUserRoles_Redundant(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+	expectAccountModel(client)
+
+	expectRoleAssignees(client, "owner", "user:jane@example.com")
+	expectRoleAssignees(client, "member", "user:jane@example.com")
+
+	audit, err := service.AuditUserRoles(ctx, rCtx, "jane@example.com")
+
+	require.NoError(t, err)
+	assert.False(t, audit.Clean())
+	assert.Equal(t, []string{"owner", "member"}, audit.Roles)
+	assert.Empty(t, audit.Duplicates)
+	assert.Equal(t, []*RedundantRole{{Role: "member", SupersededBy: "owner"}}, audit.Redundant)
+}
+
+func TestService_AuditUserRoles_ReadError(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	client.EXPECT().Read(mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+
+	audit, err := service.AuditUserRoles(ctx, rCtx, "jane@example.com")
+
+	require.Error(t, err)
+	assert.Nil(t, audit)
+	assert.Contains(t, err.Error(), "failed to read assignees of role owner")
+}
+
+func TestImpliedBy(t *testing.T) {
+	computed := func(relation string) *openfgav1.Userset {
+		return &openfgav1.Userset{Userset: &openfgav1.Userset_ComputedUserset{ComputedUserset: &openfgav1.ObjectRelation{Relation: relation}}}
+	}
+	union := func(children ...*openfgav1.Userset) *openfgav1.Userset {
+		return &openfgav1.Userset{Userset: &openfgav1.Userset_Union{Union: &openfgav1.Usersets{Child: children}}}
+	}
+	typeDef := &openfgav1.TypeDefinition{Relations: map[string]*openfgav1.Userset{
+		"admin":  computed("owner"),
+		"owner":  {},
+		"editor": union(computed("admin"), computed("viewer")),
+		"viewer": union(computed("editor")),
+		// Relations in intersections don't grant access on their own
+		"auditor": {Userset: &openfgav1.Userset_Intersection{Intersection: &openfgav1.Usersets{Child: []*openfgav1.Userset{computed("owner")}}}},
+	}}
+
+	assert.ElementsMatch(t, []string{"admin", "owner", "viewer"}, impliedBy(typeDef, "editor"))
+	assert.ElementsMatch(t, []string{"editor", "admin", "owner"}, impliedBy(typeDef, "viewer"))
+	assert.Empty(t, impliedBy(typeDef, "auditor"))
+	assert.Empty(t, impliedBy(typeDef, "unknown"))
+}
@@ -0,0 +1,121 @@
+package breaker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/platform-mesh/iam-service/pkg/config"
+)
+
+// ErrBackendUnavailable is returned without calling OpenFGA while the breaker is open
+var ErrBackendUnavailable = status.Error(codes.Unavailable, "authorization backend unavailable")
+
+type state int
+
+const (
+	stateClosed state = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// Breaker is a circuit breaker for outgoing OpenFGA calls.
+// After FailureThreshold consecutive backend failures it opens and rejects all calls for OpenTimeout.
+// Afterwards a single probe call is let through: if it succeeds the breaker closes again, otherwise it reopens.
+type Breaker struct {
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	state    state
+	failures int
+	openedAt time.Time
+}
+
+// New creates a Breaker from the config
+// Returns nil if the breaker is disabled (FailureThreshold <= 0)
+func New(cfg config.OpenFGACircuitBreakerConfig) *Breaker {
+	if cfg.FailureThreshold <= 0 {
+		return nil
+	}
+	return &Breaker{
+		threshold:   cfg.FailureThreshold,
+		openTimeout: cfg.OpenTimeout,
+		now:         time.Now,
+	}
+}
+
+// UnaryClientInterceptor guards every outgoing unary gRPC call with the breaker
+// A nil Breaker passes all calls through
+func (b *Breaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if b == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		if !b.allow() {
+			return ErrBackendUnavailable
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		b.record(isBackendFailure(err))
+		return err
+	}
+}
+
+// allow reports whether a call may be sent, moving an expired open breaker to half-open
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		// Let exactly one probe through, concurrent calls keep failing fast until it returns
+		b.state = stateHalfOpen
+		return true
+	case stateHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of a call
+func (b *Breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.state = stateClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		b.state = stateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// isBackendFailure reports whether an error indicates that OpenFGA itself is failing.
+// Errors caused by the request, e.g. invalid input or missing stores, don't count.
+func isBackendFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,145 @@
+package breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/platform-mesh/iam-service/pkg/config"
+)
+
+const testMethod = "/openfga.v1.OpenFGAService/Check"
+
+// newTestBreaker creates a breaker with a controllable clock
+func newTestBreaker(threshold int) (*Breaker, *time.Time) {
+	now := time.Now()
+	b := New(config.OpenFGACircuitBreakerConfig{FailureThreshold: threshold, OpenTimeout: 30 * time.Second})
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+// countingInvoker returns err on every call and counts the calls
+func countingInvoker(calls *int, err *error) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		return *err
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	assert.Nil(t, New(config.OpenFGACircuitBreakerConfig{}))
+
+	// A disabled breaker passes every call through
+	var b *Breaker
+	calls := 0
+	err := status.Error(codes.Unavailable, "down")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, err, b.UnaryClientInterceptor()(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &err)))
+	}
+	assert.Equal(t, 10, calls)
+}
+
+func TestBreaker_TripsAfterThreshold(t *testing.T) {
+	b, _ := newTestBreaker(3)
+	interceptor := b.UnaryClientInterceptor()
+
+	calls := 0
+	backendErr := status.Error(codes.Unavailable, "connection refused")
+	for i := 0; i < 3; i++ {
+		err := interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &backendErr))
+		assert.Equal(t, backendErr, err)
+	}
+	require.Equal(t, 3, calls)
+
+	// Open: calls fail fast without reaching OpenFGA
+	for i := 0; i < 5; i++ {
+		err := interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &backendErr))
+		assert.ErrorIs(t, err, ErrBackendUnavailable)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	assert.Equal(t, 3, calls)
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker(2)
+	interceptor := b.UnaryClientInterceptor()
+
+	calls := 0
+	backendErr := status.Error(codes.Unavailable, "connection refused")
+	var noErr error
+	_ = interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &backendErr))
+	_ = interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &noErr))
+	_ = interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &backendErr))
+
+	// Failures weren't consecutive, so the breaker is still closed
+	assert.NoError(t, interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &noErr)))
+	assert.Equal(t, 4, calls)
+}
+
+func TestBreaker_IgnoresRequestErrors(t *testing.T) {
+	b, _ := newTestBreaker(1)
+	interceptor := b.UnaryClientInterceptor()
+
+	calls := 0
+	requestErr := status.Error(codes.InvalidArgument, "invalid tuple")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, requestErr, interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &requestErr)))
+	}
+	assert.Equal(t, 3, calls)
+}
+
+func TestBreaker_RecoversAfterSuccessfulProbe(t *testing.T) {
+	b, now := newTestBreaker(1)
+	interceptor := b.UnaryClientInterceptor()
+
+	calls := 0
+	err := status.Error(codes.Unavailable, "connection refused")
+	_ = interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &err))
+	assert.ErrorIs(t, interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &err)), ErrBackendUnavailable)
+	require.Equal(t, 1, calls)
+
+	// After the open timeout a probe is let through
+	*now = now.Add(30 * time.Second)
+	err = nil
+	assert.NoError(t, interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &err)))
+	assert.Equal(t, 2, calls)
+
+	// The successful probe closed the breaker again
+	assert.NoError(t, interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &err)))
+	assert.Equal(t, 3, calls)
+}
+
+func TestBreaker_FailedProbeReopens(t *testing.T) {
+	b, now := newTestBreaker(3)
+	interceptor := b.UnaryClientInterceptor()
+
+	calls := 0
+	err := status.Error(codes.DeadlineExceeded, "timeout")
+	for i := 0; i < 3; i++ {
+		_ = interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &err))
+	}
+
+	*now = now.Add(30 * time.Second)
+	assert.Equal(t, err, interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &err)))
+	assert.Equal(t, 4, calls)
+
+	// A single failed probe is enough to open the breaker again
+	assert.ErrorIs(t, interceptor(context.Background(), testMethod, nil, nil, nil, countingInvoker(&calls, &err)), ErrBackendUnavailable)
+	assert.Equal(t, 4, calls)
+}
+
+func TestBreaker_HalfOpenAllowsSingleProbe(t *testing.T) {
+	b, now := newTestBreaker(1)
+	b.record(true)
+	*now = now.Add(time.Minute)
+
+	assert.True(t, b.allow())
+	// Further calls fail fast while the probe is in flight
+	assert.False(t, b.allow())
+}
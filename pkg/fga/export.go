@@ -0,0 +1,145 @@
+package fga
+
+import (
+	"context"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/fga/util"
+	"github.com/platform-mesh/golang-commons/logger"
+	"go.opentelemetry.io/otel"
+
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
+	"github.com/platform-mesh/iam-service/pkg/fga/tuples"
+	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/platform-mesh/iam-service/pkg/roles"
+)
+
+// ExportRoleAssignments returns the raw tuples of all role assignments of the resource in rctx:
+// the assignees of each role and the bindings of the roles to the resource.
+// The result can be written back as is, e.g. to restore a backup.
+func (s *Service) ExportRoleAssignments(ctx context.Context, rctx graph.ResourceContext) ([]*openfgav1.TupleKey, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	log = log.MustChildLoggerWithAttributes("group", rctx.Group, "kind", rctx.Kind, "resource", rctx.Resource.Name)
+	ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "fga.ExportRoleAssignments")
+	defer span.End()
+
+	clusterId, err := appcontext.GetClusterId(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster ID from context")
+	}
+
+	kctx, err := appcontext.GetKCPContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kcp user context")
+	}
+
+	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
+	}
+
+	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
+	if err != nil { // coverage-ignore
+		return nil, errors.Wrap(err, "failed to get role definitions for group resource %s/%s", rctx.Group, rctx.Kind)
+	}
+
+	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+	resource := rctx.Resource
+	resourceObject := tuples.ResourceObject(fgaTypeName, clusterId, resource.Namespace, resource.Name)
+
+	var result []*openfgav1.TupleKey
+	for _, role := range roles.GetAvailableRoleIDs(roleDefinitions) {
+		assignees, err := s.readAll(ctx, storeID, &openfgav1.ReadRequestTupleKey{
+			Relation: tuples.AssigneeRelation,
+			Object:   tuples.RoleObject(fgaTypeName, clusterId, resource.Name, role),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read assignees of role %s on resource %s", role, resource.Name)
+		}
+		for _, assignee := range assignees {
+			result = append(result, assignee.Key)
+		}
+
+		bindings, err := s.readAll(ctx, storeID, &openfgav1.ReadRequestTupleKey{
+			Relation: role,
+			Object:   resourceObject,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read bindings of role %s on resource %s", role, resource.Name)
+		}
+		// Other tuples with the role as relation are not part of the role assignments
+		userset := tuples.RoleAssigneeUserset(fgaTypeName, clusterId, resource.Name, role)
+		for _, binding := range bindings {
+			if binding.Key.User == userset {
+				result = append(result, binding.Key)
+			}
+		}
+	}
+	log.Info().Int("tuples", len(result)).Msg("Exported role assignments")
+
+	return result, nil
+}
+
+// ImportRoleAssignments writes tuples previously returned by ExportRoleAssignments to the resource in rctx.
+// All tuples are validated before anything is written: each one must either assign a user to one of the
+// roles of the resource or bind such a role to the resource. Tuples that already exist are skipped.
+func (s *Service) ImportRoleAssignments(ctx context.Context, rctx graph.ResourceContext, tupleKeys []*openfgav1.TupleKey) error {
+	log := logger.LoadLoggerFromContext(ctx)
+	log = log.MustChildLoggerWithAttributes("group", rctx.Group, "kind", rctx.Kind, "resource", rctx.Resource.Name)
+	ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "fga.ImportRoleAssignments")
+	defer span.End()
+
+	clusterId, err := appcontext.GetClusterId(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster ID from context")
+	}
+
+	kctx, err := appcontext.GetKCPContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get kcp user context")
+	}
+
+	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
+	if err != nil { // coverage-ignore
+		return errors.Wrap(err, "failed to get role definitions for group resource %s/%s", rctx.Group, rctx.Kind)
+	}
+
+	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+	resource := rctx.Resource
+	resourceObject := tuples.ResourceObject(fgaTypeName, clusterId, resource.Namespace, resource.Name)
+
+	// Maps the objects of assignee tuples and the usersets of binding tuples to their role
+	roleObjects := map[string]string{}
+	roleUsersets := map[string]string{}
+	for _, role := range roles.GetAvailableRoleIDs(roleDefinitions) {
+		roleObjects[tuples.RoleObject(fgaTypeName, clusterId, resource.Name, role)] = role
+		roleUsersets[tuples.RoleAssigneeUserset(fgaTypeName, clusterId, resource.Name, role)] = role
+	}
+
+	for _, tupleKey := range tupleKeys {
+		if tupleKey == nil {
+			return errors.New("invalid role assignment tuple: tuple is empty")
+		}
+		_, isAssignee := roleObjects[tupleKey.Object]
+		isAssignee = isAssignee && tupleKey.Relation == tuples.AssigneeRelation && strings.HasPrefix(tupleKey.User, tuples.UserType+":")
+		role, isBinding := roleUsersets[tupleKey.User]
+		isBinding = isBinding && tupleKey.Relation == role && tupleKey.Object == resourceObject
+		if !isAssignee && !isBinding {
+			return errors.New("invalid role assignment tuple %s %s %s for resource %s", tupleKey.User, tupleKey.Relation, tupleKey.Object, resource.Name)
+		}
+	}
+
+	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
+	}
+
+	if _, err := s.writeIgnoringDuplicates(ctx, storeID, tupleKeys, log); err != nil {
+		return errors.Wrap(err, "failed to write role assignments for resource %s", resource.Name)
+	}
+	log.Info().Int("tuples", len(tupleKeys)).Msg("Imported role assignments")
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package fga
+
+import (
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	fgamocks "github.com/platform-mesh/iam-service/pkg/fga/mocks"
+)
+
+func expectRoleBindings(client *fgamocks.OpenFGAServiceClient, role string, users ...string) {
+	object := "core_platform-mesh_io_account:cluster-123/test-account"
+	res := &openfgav1.ReadResponse{}
+	for _, user := range users {
+		res.Tuples = append(res.Tuples, &openfgav1.Tuple{
+			Key: &openfgav1.TupleKey{User: user, Relation: role, Object: object},
+		})
+	}
+	client.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+		return req.StoreId == "store-123" && req.GetTupleKey().GetRelation() == role && req.GetTupleKey().GetObject() == object
+	})).Return(res, nil).Once()
+}
+
+func TestService_ExportRoleAssignments(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:owner@example.com")
+	expectRoleBindings(client, "owner",
+		"role:core_platform-mesh_io_account/cluster-123/test-account/owner#assignee",
+		// Not a role binding, e.g. a tuple written by another component
+		"user:direct@example.com",
+	)
+	expectRoleAssignees(client, "member", "user:member1@example.com", "user:member2@example.com")
+	expectRoleBindings(client, "member", "role:core_platform-mesh_io_account/cluster-123/test-account/member#assignee")
+
+	exported, err := service.ExportRoleAssignments(ctx, rCtx)
+
+	require.NoError(t, err)
+	var keys []string
+	for _, key := range exported {
+		keys = append(keys, key.User+" "+key.Relation+" "+key.Object)
+	}
+	assert.ElementsMatch(t, []string{
+		"user:owner@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/owner",
+		"role:core_platform-mesh_io_account/cluster-123/test-account/owner#assignee owner core_platform-mesh_io_account:cluster-123/test-account",
+		"user:member1@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/member",
+		"user:member2@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/member",
+		"role:core_platform-mesh_io_account/cluster-123/test-account/member#assignee member core_platform-mesh_io_account:cluster-123/test-account",
+	}, keys)
+}
+
+func TestService_ExportRoleAssignments_NoAssignments(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner")
+	expectRoleBindings(client, "owner")
+	expectRoleAssignees(client, "member")
+	expectRoleBindings(client, "member")
+
+	exported, err := service.ExportRoleAssignments(ctx, rCtx)
+
+	require.NoError(t, err)
+	assert.Empty(t, exported)
+}
+
+func TestService_ExportRoleAssignments_ReadError(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:owner@example.com")
+	client.EXPECT().Read(mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+
+	exported, err := service.ExportRoleAssignments(ctx, rCtx)
+
+	require.Error(t, err)
+	assert.Nil(t, exported)
+	assert.Contains(t, err.Error(), "failed to read bindings of role owner")
+}
+
+func TestService_ImportRoleAssignments(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	tupleKeys := []*openfgav1.TupleKey{
+		{User: "user:owner@example.com", Relation: "assignee", Object: "role:core_platform-mesh_io_account/cluster-123/test-account/owner"},
+		{User: "role:core_platform-mesh_io_account/cluster-123/test-account/owner#assignee", Relation: "owner", Object: "core_platform-mesh_io_account:cluster-123/test-account"},
+		{User: "user:member@example.com", Relation: "assignee", Object: "role:core_platform-mesh_io_account/cluster-123/test-account/member"},
+		{User: "role:core_platform-mesh_io_account/cluster-123/test-account/member#assignee", Relation: "member", Object: "core_platform-mesh_io_account:cluster-123/test-account"},
+	}
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return req.StoreId == "store-123" && req.Deletes == nil && len(req.Writes.TupleKeys) == len(tupleKeys)
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+
+	err := service.ImportRoleAssignments(ctx, rCtx, tupleKeys)
+
+	require.NoError(t, err)
+}
+
+func TestService_ImportRoleAssignments_RejectsInvalidTuples(t *testing.T) {
+	valid := &openfgav1.TupleKey{User: "user:owner@example.com", Relation: "assignee", Object: "role:core_platform-mesh_io_account/cluster-123/test-account/owner"}
+
+	tests := []struct {
+		name     string
+		tupleKey *openfgav1.TupleKey
+	}{
+		{name: "empty tuple", tupleKey: nil},
+		{name: "unknown role", tupleKey: &openfgav1.TupleKey{User: "user:owner@example.com", Relation: "assignee", Object: "role:core_platform-mesh_io_account/cluster-123/test-account/admin"}},
+		{name: "role of other resource", tupleKey: &openfgav1.TupleKey{User: "user:owner@example.com", Relation: "assignee", Object: "role:core_platform-mesh_io_account/cluster-123/other-account/owner"}},
+		{name: "assignee is not a user", tupleKey: &openfgav1.TupleKey{User: "group:admins", Relation: "assignee", Object: "role:core_platform-mesh_io_account/cluster-123/test-account/owner"}},
+		{name: "binding with other relation", tupleKey: &openfgav1.TupleKey{User: "role:core_platform-mesh_io_account/cluster-123/test-account/owner#assignee", Relation: "member", Object: "core_platform-mesh_io_account:cluster-123/test-account"}},
+		{name: "binding to other resource", tupleKey: &openfgav1.TupleKey{User: "role:core_platform-mesh_io_account/cluster-123/test-account/owner#assignee", Relation: "owner", Object: "core_platform-mesh_io_account:cluster-123/other-account"}},
+		{name: "direct permission", tupleKey: &openfgav1.TupleKey{User: "user:owner@example.com", Relation: "owner", Object: "core_platform-mesh_io_account:cluster-123/test-account"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Nothing is written if a single tuple is invalid
+			service, _ := createTestService(t)
+			ctx, rCtx := createPreviewTestContext()
+
+			err := service.ImportRoleAssignments(ctx, rCtx, []*openfgav1.TupleKey{valid, tt.tupleKey})
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid role assignment tuple")
+		})
+	}
+}
+
+func TestService_ImportRoleAssignments_SkipsDuplicates(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	tupleKeys := []*openfgav1.TupleKey{
+		{User: "user:owner@example.com", Relation: "assignee", Object: "role:core_platform-mesh_io_account/cluster-123/test-account/owner"},
+		{User: "role:core_platform-mesh_io_account/cluster-123/test-account/owner#assignee", Relation: "owner", Object: "core_platform-mesh_io_account:cluster-123/test-account"},
+	}
+	duplicate := status.Error(codes.Code(openfgav1.ErrorCode_write_failed_due_to_invalid_input), "tuple already exists")
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 2
+	})).Return(nil, duplicate).Once()
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 1 && req.Writes.TupleKeys[0].Relation == "assignee"
+	})).Return(nil, duplicate).Once()
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 1 && req.Writes.TupleKeys[0].Relation == "owner"
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+
+	err := service.ImportRoleAssignments(ctx, rCtx, tupleKeys)
+
+	require.NoError(t, err)
+}
+
+func TestService_ImportRoleAssignments_WriteError(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	client.EXPECT().Write(mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+
+	err := service.ImportRoleAssignments(ctx, rCtx, []*openfgav1.TupleKey{
+		{User: "user:owner@example.com", Relation: "assignee", Object: "role:core_platform-mesh_io_account/cluster-123/test-account/owner"},
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to write role assignments for resource test-account")
+}
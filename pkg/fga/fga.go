@@ -3,6 +3,8 @@ package fga
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
@@ -15,7 +17,9 @@ import (
 	"github.com/platform-mesh/iam-service/pkg/config"
 	appcontext "github.com/platform-mesh/iam-service/pkg/context"
 	"github.com/platform-mesh/iam-service/pkg/fga/store"
+	"github.com/platform-mesh/iam-service/pkg/fga/tuples"
 	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/platform-mesh/iam-service/pkg/redact"
 	"github.com/platform-mesh/iam-service/pkg/roles"
 	"github.com/platform-mesh/iam-service/pkg/workspace"
 )
@@ -24,14 +28,13 @@ var (
 	userFilter = []*openfgav1.UserTypeFilter{{Type: "user"}}
 )
 
-// sanitizeUserID returns a sanitized version of the userID for logging (first 3 chars + ***)
-// to avoid logging PII information
-func sanitizeUserID(userID string) string {
-	if len(userID) <= 3 {
-		return userID
-	}
-	return userID[:3] + "***"
-}
+const (
+	// ownerRelation is the relation binding the owner role to a resource. It is also the owner role
+	// of group resources without role definitions.
+	ownerRelation = "owner"
+	// parentRelation links a resource to the resource it inherits permissions from
+	parentRelation = "parent"
+)
 
 type UserIDToRoles map[string][]string
 
@@ -41,11 +44,13 @@ type IDMUserChecker interface {
 }
 
 type Service struct {
-	client          openfgav1.OpenFGAServiceClient
-	helper          store.StoreHelper
-	rolesRetriever  roles.RolesRetriever
-	wsClientFactory workspace.ClientFactory
-	idmChecker      IDMUserChecker
+	client            openfgav1.OpenFGAServiceClient
+	helper            store.StoreHelper
+	rolesRetriever    roles.RolesRetriever
+	wsClientFactory   workspace.ClientFactory
+	idmChecker        IDMUserChecker
+	maxTuplesPerWrite int
+	invitePolicy      config.InviteConfig
 }
 
 func New(client openfgav1.OpenFGAServiceClient, cfg *config.ServiceConfig, wsClientFactory workspace.ClientFactory, idmChecker IDMUserChecker) (*Service, error) {
@@ -56,11 +61,13 @@ func New(client openfgav1.OpenFGAServiceClient, cfg *config.ServiceConfig, wsCli
 	}
 
 	return &Service{
-		client:          client,
-		helper:          store.NewFGAStoreHelper(cfg.OpenFGA.StoreCacheTTL),
-		rolesRetriever:  rolesRetriever,
-		wsClientFactory: wsClientFactory,
-		idmChecker:      idmChecker,
+		client:            client,
+		helper:            store.NewFGAStoreHelper(cfg.OpenFGA.StoreCacheTTL),
+		rolesRetriever:    rolesRetriever,
+		wsClientFactory:   wsClientFactory,
+		idmChecker:        idmChecker,
+		maxTuplesPerWrite: cfg.OpenFGA.MaxTuplesPerWrite,
+		invitePolicy:      cfg.Invite,
 	}, nil
 }
 
@@ -68,17 +75,39 @@ func New(client openfgav1.OpenFGAServiceClient, cfg *config.ServiceConfig, wsCli
 func NewWithRolesRetriever(client openfgav1.OpenFGAServiceClient, cfg *config.ServiceConfig, rolesRetriever roles.RolesRetriever) *Service {
 	helper := store.NewFGAStoreHelper(cfg.OpenFGA.StoreCacheTTL)
 	return &Service{
-		client:         client,
-		helper:         helper,
-		rolesRetriever: rolesRetriever,
+		client:            client,
+		helper:            helper,
+		rolesRetriever:    rolesRetriever,
+		maxTuplesPerWrite: cfg.OpenFGA.MaxTuplesPerWrite,
+		invitePolicy:      cfg.Invite,
 	}
 }
 
+// WarmCaches resolves the store and authorization model IDs of the organizations ahead of the first request
+func (s *Service) WarmCaches(ctx context.Context, orgIDs []string) error {
+	return store.WarmUp(ctx, s.helper, s.client, orgIDs)
+}
+
 func (s *Service) ListUsers(ctx context.Context, rctx graph.ResourceContext, roleFilters []string) ([]*graph.UserRoles, error) {
-	log := logger.LoadLoggerFromContext(ctx)
 	ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "fga.ListUsers")
 	defer span.End()
 
+	return s.listUsers(ctx, rctx, roleFilters, false)
+}
+
+// ListUsersWithEffectiveRoles works like ListUsers but reports the roles users hold according to the
+// authorization model instead of only the explicitly assigned ones, e.g. an owner is also reported as
+// member if the model grants member to owners.
+func (s *Service) ListUsersWithEffectiveRoles(ctx context.Context, rctx graph.ResourceContext, roleFilters []string) ([]*graph.UserRoles, error) {
+	ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "fga.ListUsersWithEffectiveRoles")
+	defer span.End()
+
+	return s.listUsers(ctx, rctx, roleFilters, true)
+}
+
+func (s *Service) listUsers(ctx context.Context, rctx graph.ResourceContext, roleFilters []string, effective bool) ([]*graph.UserRoles, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+
 	kctx, err := appcontext.GetKCPContext(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get kcp user context")
@@ -100,7 +129,9 @@ func (s *Service) ListUsers(ctx context.Context, rctx graph.ResourceContext, rol
 	}
 
 	// Use parallel processing for multiple roles
-	return s.listUsersParallel(ctx, rctx, storeID, appliedRoles)
+	return store.WithModelRetry(ctx, s.helper, s.client, kctx.OrganizationName, func(modelID string) ([]*graph.UserRoles, error) {
+		return s.listUsersParallel(ctx, rctx, storeID, modelID, appliedRoles, effective)
+	})
 }
 
 func (s *Service) CountUsersForRole(ctx context.Context, rctx graph.ResourceContext, roleID string) (int, error) {
@@ -112,8 +143,150 @@ func (s *Service) CountUsersForRole(ctx context.Context, rctx graph.ResourceCont
 	return len(users), nil
 }
 
-// listUsersParallel performs parallel ListUsers calls for multiple roles
-func (s *Service) listUsersParallel(ctx context.Context, rctx graph.ResourceContext, storeID string, roles []string) ([]*graph.UserRoles, error) {
+// Permissions returns every permission relation the authorization model defines on the resource type,
+// sorted by name. Relations which bind roles from the roles file and the parent relation are excluded.
+func (s *Service) Permissions(ctx context.Context, rctx graph.ResourceContext) ([]string, error) {
+	kctx, err := appcontext.GetKCPContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kcp user context")
+	}
+
+	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
+	}
+
+	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+	typeDef, err := s.readTypeDefinition(ctx, storeID, kctx.OrganizationName, fgaTypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
+	if err != nil { // coverage-ignore
+		return nil, errors.Wrap(err, "failed to get role definitions for group resource %s/%s", rctx.Group, rctx.Kind)
+	}
+	return permissionRelations(typeDef, roles.GetAvailableRoleIDs(roleDefinitions)), nil
+}
+
+// permissionRelations returns the relations of the type definition that are permissions, sorted by name,
+// i.e. all relations except the parent relation and the ones binding the given roles
+func permissionRelations(typeDef *openfgav1.TypeDefinition, roleIDs []string) []string {
+	var permissions []string
+	for relation := range typeDef.GetRelations() {
+		if relation == parentRelation || containsString(roleIDs, relation) {
+			continue
+		}
+		permissions = append(permissions, relation)
+	}
+	slices.Sort(permissions)
+	return permissions
+}
+
+// MissingRoleRelations returns the expected roles for which the authorization model defines no relation
+// on the type of the group resource, in the order they were given. Roles can't be bound to resources
+// of the type without such a relation, so a non-empty result means the deployed model is out of date.
+func (s *Service) MissingRoleRelations(ctx context.Context, group, kind string, expectedRoles []string) ([]string, error) {
+	kctx, err := appcontext.GetKCPContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kcp user context")
+	}
+
+	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
+	}
+
+	fgaTypeName := util.ConvertToTypeName(group, kind)
+	typeDef, err := s.readTypeDefinition(ctx, storeID, kctx.OrganizationName, fgaTypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, role := range expectedRoles {
+		if _, ok := typeDef.GetRelations()[role]; !ok {
+			missing = append(missing, role)
+		}
+	}
+	return missing, nil
+}
+
+// ValidateModel checks that the authorization models of the organizations define a relation for every role of
+// the roles file on the type of its group resource. The returned error lists all missing relations.
+func (s *Service) ValidateModel(ctx context.Context, orgIDs []string) error {
+	var problems []string
+	for _, orgID := range orgIDs {
+		orgCtx := appcontext.SetKCPContext(ctx, appcontext.KCPContext{OrganizationName: orgID})
+		for _, groupResource := range s.rolesRetriever.GroupResources() {
+			group, kind, found := strings.Cut(groupResource, "/")
+			if !found {
+				group, kind = "", groupResource
+			}
+
+			roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(graph.ResourceContext{Group: group, Kind: kind})
+			if err != nil { // coverage-ignore
+				return errors.Wrap(err, "failed to get role definitions for group resource %s", groupResource)
+			}
+
+			missing, err := s.MissingRoleRelations(orgCtx, group, kind, roles.GetAvailableRoleIDs(roleDefinitions))
+			if err != nil {
+				return errors.Wrap(err, "failed to validate authorization model of organization %s", orgID)
+			}
+			if len(missing) > 0 {
+				problems = append(problems, fmt.Sprintf("%s: %s misses %s", orgID, groupResource, strings.Join(missing, ", ")))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("authorization model lacks role relations (%s)", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// readTypeDefinition returns the definition of the FGA type in the authorization model of the organization
+func (s *Service) readTypeDefinition(ctx context.Context, storeID, orgID, fgaTypeName string) (*openfgav1.TypeDefinition, error) {
+	res, err := store.WithModelRetry(ctx, s.helper, s.client, orgID, func(modelID string) (*openfgav1.ReadAuthorizationModelResponse, error) {
+		res, err := s.client.ReadAuthorizationModel(ctx, &openfgav1.ReadAuthorizationModelRequest{
+			StoreId: storeID,
+			Id:      modelID,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read authorization model %s", modelID)
+		}
+		return res, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, typeDef := range res.GetAuthorizationModel().GetTypeDefinitions() {
+		if typeDef.GetType() == fgaTypeName {
+			return typeDef, nil
+		}
+	}
+	return nil, errors.New("type %s is not defined in authorization model %s", fgaTypeName, res.GetAuthorizationModel().GetId())
+}
+
+// OwnerRole returns the ID of the highest ranked role of the group resource, see roles.RoleDefinition.Priority.
+// Falls back to the owner role if the group resource has no role definitions.
+func (s *Service) OwnerRole(rctx graph.ResourceContext) (string, error) {
+	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
+	if err != nil { // coverage-ignore
+		return "", errors.Wrap(err, "failed to get role definitions for group resource %s/%s", rctx.Group, rctx.Kind)
+	}
+
+	if ownerRole := roles.OwnerRoleID(roleDefinitions); ownerRole != "" {
+		return ownerRole, nil
+	}
+	return ownerRelation, nil
+}
+
+// listUsersParallel performs parallel ListUsers calls for multiple roles.
+// Explicit assignments are read from the assignee relation of the role objects, effective roles
+// from the role relation on the resource itself, which the model may derive from other roles.
+func (s *Service) listUsersParallel(ctx context.Context, rctx graph.ResourceContext, storeID, modelID string, roles []string, effective bool) ([]*graph.UserRoles, error) {
 
 	type roleResult struct {
 		role  string
@@ -134,18 +307,22 @@ func (s *Service) listUsersParallel(ctx context.Context, rctx graph.ResourceCont
 	for _, role := range roles {
 		go func(role string) {
 			req := &openfgav1.ListUsersRequest{
-				StoreId: storeID,
+				StoreId:              storeID,
+				AuthorizationModelId: modelID,
 				Object: &openfgav1.Object{
-					Type: "role",
-					Id: fmt.Sprintf("%s/%s/%s/%s",
-						fgaTypeName,
-						clusterId,
-						rctx.Resource.Name,
-						role),
+					Type: tuples.RoleType,
+					Id:   tuples.RoleObjectID(fgaTypeName, clusterId, rctx.Resource.Name, role),
 				},
-				Relation:    "assignee",
+				Relation:    tuples.AssigneeRelation,
 				UserFilters: userFilter,
 			}
+			if effective {
+				req.Object = &openfgav1.Object{
+					Type: fgaTypeName,
+					Id:   tuples.ResourceObjectID(clusterId, rctx.Resource.Namespace, rctx.Resource.Name),
+				}
+				req.Relation = role
+			}
 
 			users, err := s.client.ListUsers(ctx, req)
 			resultChan <- roleResult{
@@ -192,12 +369,6 @@ func (s *Service) convertToGraphUserRoles(rctx graph.ResourceContext, userIDToRo
 		roleDefinitions = []roles.RoleDefinition{}
 	}
 
-	// Create a map for quick role definition lookup
-	roleDefMap := make(map[string]roles.RoleDefinition)
-	for _, roleDef := range roleDefinitions {
-		roleDefMap[roleDef.ID] = roleDef
-	}
-
 	for userID, roleNames := range userIDToRoles {
 		// Create User with available information (only userID from OpenFGA)
 		user := &graph.User{
@@ -205,10 +376,10 @@ func (s *Service) convertToGraphUserRoles(rctx graph.ResourceContext, userIDToRo
 			Email:  userID, // Not available from OpenFGA ListUsers response
 		}
 
-		// Convert role names to Role objects
+		// Convert role names to Role objects, highest ranked role first
 		var rArr []*graph.Role
-		for _, roleName := range roleNames {
-			if roleDef, exists := roleDefMap[roleName]; exists {
+		for _, roleDef := range roleDefinitions {
+			if slices.Contains(roleNames, roleDef.ID) {
 				role := &graph.Role{
 					ID:          roleDef.ID,
 					DisplayName: roleDef.DisplayName,
@@ -296,24 +467,27 @@ func (s *Service) AssignRolesToUsers(ctx context.Context, rctx graph.ResourceCon
 	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
 
 	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
-	if err != nil { // coverage-ignore
+	if err != nil {
 		return nil, errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
 	}
 
 	var allErrors []string
 	var totalAssigned int
+	// Roles are bound to the resource once after all assignments
+	var assignedRoles []string
 
 	// Process invites first - create Invite resources for users that don't exist
 	// and then assign their roles
 	if len(invites) > 0 {
-		invitedCount, inviteErrors := s.processInvites(ctx, rctx, invites, storeID, fgaTypeName, clusterId, log)
+		invitedCount, inviteErrors, invitedRoles := s.processInvites(ctx, rctx, invites, storeID, fgaTypeName, clusterId, log)
 		totalAssigned += invitedCount
 		allErrors = append(allErrors, inviteErrors...)
+		assignedRoles = append(assignedRoles, invitedRoles...)
 	}
 
 	// Process regular user role changes (for existing users)
 	for _, change := range changes {
-		changeLog := log.MustChildLoggerWithAttributes("userId", sanitizeUserID(change.UserID))
+		changeLog := log.MustChildLoggerWithAttributes("userId", redact.Email(change.UserID))
 		changeLog.Debug().Interface("roles", change.Roles).Msg("Processing role assignment")
 
 		// Validate that only available roles are being assigned
@@ -329,7 +503,7 @@ func (s *Service) AssignRolesToUsers(ctx context.Context, rctx graph.ResourceCon
 		for _, role := range change.Roles {
 			roleLog := changeLog.MustChildLoggerWithAttributes("role", role)
 			if !containsString(availableRoles, role) {
-				errMsg := fmt.Sprintf("role '%s' is not allowed for user '%s'. Only roles %v are permitted", role, sanitizeUserID(change.UserID), availableRoles)
+				errMsg := fmt.Sprintf("role '%s' is not allowed for user '%s'. Only roles %v are permitted", role, redact.Email(change.UserID), availableRoles)
 				allErrors = append(allErrors, errMsg)
 				roleLog.Warn().Interface("availableRoles", availableRoles).Msg("Invalid role assignment attempted")
 				continue
@@ -339,9 +513,16 @@ func (s *Service) AssignRolesToUsers(ctx context.Context, rctx graph.ResourceCon
 			count, errs := s.assignRoleToUser(ctx, change.UserID, role, rctx, storeID, fgaTypeName, clusterId, roleLog)
 			totalAssigned += count
 			allErrors = append(allErrors, errs...)
+			if !slices.Contains(assignedRoles, role) {
+				assignedRoles = append(assignedRoles, role)
+			}
 		}
 	}
 
+	boundCount, bindErrors := s.bindRoles(ctx, rctx, assignedRoles, storeID, fgaTypeName, clusterId, log)
+	totalAssigned += boundCount
+	allErrors = append(allErrors, bindErrors...)
+
 	// Determine overall success
 	success := len(allErrors) == 0
 
@@ -375,7 +556,7 @@ func (s *Service) RemoveRole(ctx context.Context, rctx graph.ResourceContext, in
 		return nil, errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
 	}
 
-	log.Debug().Str("userId", sanitizeUserID(input.UserID)).Str("role", input.Role).Msg("Processing role removal")
+	log.Debug().Str("userId", redact.Email(input.UserID)).Str("role", input.Role).Msg("Processing role removal")
 
 	// Validate that only available roles can be removed
 	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
@@ -392,7 +573,7 @@ func (s *Service) RemoveRole(ctx context.Context, rctx graph.ResourceContext, in
 
 	if !containsString(availableRoles, input.Role) {
 		errMsg := fmt.Sprintf("role '%s' is not allowed. Only roles %v are permitted", input.Role, availableRoles)
-		log.Warn().Str("role", input.Role).Str("userId", sanitizeUserID(input.UserID)).Interface("availableRoles", availableRoles).Msg("Invalid role removal attempted")
+		log.Warn().Str("role", input.Role).Str("userId", redact.Email(input.UserID)).Interface("availableRoles", availableRoles).Msg("Invalid role removal attempted")
 		return &graph.RoleRemovalResult{
 			Success:     false,
 			Error:       &errMsg,
@@ -400,25 +581,10 @@ func (s *Service) RemoveRole(ctx context.Context, rctx graph.ResourceContext, in
 		}, nil
 	}
 
-	// First, check if the tuple exists by trying to read it
-	readTuple := &openfgav1.ReadRequestTupleKey{
-		User:     fmt.Sprintf("user:%s", input.UserID),
-		Relation: "assignee",
-		Object: fmt.Sprintf("role:%s/%s/%s/%s",
-			fgaTypeName,
-			clusterId,
-			rctx.Resource.Name,
-			input.Role),
-	}
-
-	readReq := &openfgav1.ReadRequest{
-		StoreId:  storeID,
-		TupleKey: readTuple,
-	}
-
-	readResp, err := s.client.Read(ctx, readReq)
+	// First, check if the role is assigned
+	wasAssigned, err := s.userHasRole(ctx, storeID, kctx.OrganizationName, tuples.RoleObject(fgaTypeName, clusterId, rctx.Resource.Name, input.Role), input.UserID)
 	if err != nil {
-		log.Error().Err(err).Str("role", input.Role).Str("userId", sanitizeUserID(input.UserID)).Msg("Failed to check if tuple exists")
+		log.Error().Err(err).Str("role", input.Role).Str("userId", redact.Email(input.UserID)).Msg("Failed to check if tuple exists")
 		errMsg := fmt.Sprintf("failed to check role assignment: %v", err)
 		return &graph.RoleRemovalResult{
 			Success:     false,
@@ -426,11 +592,8 @@ func (s *Service) RemoveRole(ctx context.Context, rctx graph.ResourceContext, in
 			WasAssigned: false,
 		}, nil
 	}
-
-	// Check if the tuple was found
-	wasAssigned := len(readResp.Tuples) > 0
 	if !wasAssigned {
-		log.Info().Str("role", input.Role).Str("userId", sanitizeUserID(input.UserID)).Msg("Role was not assigned to user - nothing to remove")
+		log.Info().Str("role", input.Role).Str("userId", redact.Email(input.UserID)).Msg("Role was not assigned to user - nothing to remove")
 		return &graph.RoleRemovalResult{
 			Success:     true,
 			Error:       nil,
@@ -440,13 +603,9 @@ func (s *Service) RemoveRole(ctx context.Context, rctx graph.ResourceContext, in
 
 	// Delete the tuple from FGA
 	deleteTuple := &openfgav1.TupleKeyWithoutCondition{
-		User:     fmt.Sprintf("user:%s", input.UserID),
-		Relation: "assignee",
-		Object: fmt.Sprintf("role:%s/%s/%s/%s",
-			fgaTypeName,
-			clusterId,
-			rctx.Resource.Name,
-			input.Role),
+		User:     tuples.User(input.UserID),
+		Relation: tuples.AssigneeRelation,
+		Object:   tuples.RoleObject(fgaTypeName, clusterId, rctx.Resource.Name, input.Role),
 	}
 
 	deleteReq := &openfgav1.WriteRequest{
@@ -458,8 +617,8 @@ func (s *Service) RemoveRole(ctx context.Context, rctx graph.ResourceContext, in
 
 	_, err = s.client.Write(ctx, deleteReq)
 	if err != nil {
-		log.Error().Err(err).Str("role", input.Role).Str("userId", sanitizeUserID(input.UserID)).Msg("Failed to delete tuple from FGA")
-		errMsg := fmt.Sprintf("failed to remove role '%s' from user '%s': %v", input.Role, sanitizeUserID(input.UserID), err)
+		log.Error().Err(err).Str("role", input.Role).Str("userId", redact.Email(input.UserID)).Msg("Failed to delete tuple from FGA")
+		errMsg := fmt.Sprintf("failed to remove role '%s' from user '%s': %v", input.Role, redact.Email(input.UserID), err)
 		return &graph.RoleRemovalResult{
 			Success:     false,
 			Error:       &errMsg,
@@ -467,7 +626,7 @@ func (s *Service) RemoveRole(ctx context.Context, rctx graph.ResourceContext, in
 		}, nil
 	}
 
-	log.Info().Str("role", input.Role).Str("userId", sanitizeUserID(input.UserID)).Msg("Successfully removed role from user")
+	log.Info().Str("role", input.Role).Str("userId", redact.Email(input.UserID)).Msg("Successfully removed role from user")
 	return &graph.RoleRemovalResult{
 		Success:     true,
 		Error:       nil,
@@ -2,6 +2,7 @@ package fga
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"sort"
 	"testing"
@@ -12,6 +13,9 @@ import (
 	"github.com/platform-mesh/golang-commons/logger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 
@@ -156,9 +160,14 @@ func TestService_ListUsers_Success(t *testing.T) {
 		},
 	}
 
+	client.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}, nil)
+
 	// Expect calls for owner and member roles
 	client.EXPECT().ListUsers(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListUsersRequest) bool {
 		return req.StoreId == storeID &&
+			req.AuthorizationModelId == "model-123" &&
 			req.Object.Type == "role" &&
 			req.Object.Id == "core_platform-mesh_io_account/cluster-123/test-account/owner" &&
 			req.Relation == "assignee"
@@ -199,6 +208,45 @@ func TestService_ListUsers_Success(t *testing.T) {
 	assert.Equal(t, expected, resultMap)
 }
 
+func TestService_ListUsers_NarrowFilterReadsOnlyFilteredRole(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	// The mock fails the test on a ListUsers call for any other role
+	client.EXPECT().ListUsers(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListUsersRequest) bool {
+		return req.Object.Id == "core_platform-mesh_io_account/cluster-123/test-account/owner"
+	})).Return(&openfgav1.ListUsersResponse{
+		Users: []*openfgav1.User{
+			{User: &openfgav1.User_Object{Object: &openfgav1.Object{Type: "user", Id: "user1"}}},
+		},
+	}, nil).Once()
+
+	result, err := service.ListUsers(ctx, rCtx, []string{"owner"})
+
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "user1", result[0].User.Email)
+	require.Len(t, result[0].Roles, 1)
+	assert.Equal(t, "owner", result[0].Roles[0].ID)
+}
+
+func TestService_EmptyOrganization(t *testing.T) {
+	// No OpenFGA request may be sent for an empty organization
+	service, _ := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{IDMTenant: "test-tenant"})
+
+	_, err := service.ListUsers(ctx, rCtx, nil)
+	assert.ErrorContains(t, err, "organization must not be empty")
+
+	_, err = service.AssignRolesToUsers(ctx, rCtx, []*graph.UserRoleChange{{UserID: "user@example.com", Roles: []string{"member"}}}, nil)
+	assert.ErrorContains(t, err, "organization must not be empty")
+
+	_, err = service.ExportRoleAssignments(ctx, rCtx)
+	assert.ErrorContains(t, err, "organization must not be empty")
+}
+
 func TestService_ListUsers_NoKCPContext(t *testing.T) {
 	service, _ := createTestService(t)
 
@@ -262,6 +310,9 @@ func TestService_CountUsersForRole(t *testing.T) {
 		},
 	}, nil)
 
+	client.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}, nil)
 	client.EXPECT().ListUsers(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListUsersRequest) bool {
 		return req.StoreId == "store-123" &&
 			req.Object.Type == "role" &&
@@ -288,6 +339,266 @@ func TestService_CountUsersForRole(t *testing.T) {
 	assert.Equal(t, 2, count)
 }
 
+func TestService_Permissions(t *testing.T) {
+	service, client := createTestService(t)
+
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant:        "test-tenant",
+		OrganizationName: "test-org",
+	})
+
+	rCtx := graph.ResourceContext{
+		Group:    "core.platform-mesh.io",
+		Kind:     "Account",
+		Resource: &graph.Resource{Name: "test-account"},
+	}
+
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	client.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}, nil)
+	client.EXPECT().ReadAuthorizationModel(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadAuthorizationModelRequest) bool {
+		return req.StoreId == "store-123" && req.Id == "model-123"
+	})).Return(&openfgav1.ReadAuthorizationModelResponse{
+		AuthorizationModel: &openfgav1.AuthorizationModel{
+			Id: "model-123",
+			TypeDefinitions: []*openfgav1.TypeDefinition{
+				{
+					Type: "core_platform-mesh_io_account",
+					Relations: map[string]*openfgav1.Userset{
+						"parent":           {},
+						"owner":            {},
+						"member":           {},
+						"get":              {},
+						"update":           {},
+						"delete":           {},
+						"manage_iam_roles": {},
+					},
+				},
+				{
+					Type:      "apps_deployment",
+					Relations: map[string]*openfgav1.Userset{"scale": {}},
+				},
+			},
+		},
+	}, nil)
+
+	permissions, err := service.Permissions(ctx, rCtx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"delete", "get", "manage_iam_roles", "update"}, permissions)
+}
+
+func TestService_MissingRoleRelations(t *testing.T) {
+	tests := []struct {
+		name      string
+		relations map[string]*openfgav1.Userset
+		expected  []string
+	}{
+		{
+			name:      "complete model",
+			relations: map[string]*openfgav1.Userset{"parent": {}, "owner": {}, "member": {}, "get": {}},
+			expected:  nil,
+		},
+		{
+			name:      "model missing a role",
+			relations: map[string]*openfgav1.Userset{"parent": {}, "owner": {}, "get": {}},
+			expected:  []string{"member"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, client := createTestService(t)
+			ctx, _ := createPreviewTestContext()
+			expectPreviewStore(client)
+
+			client.EXPECT().ReadAuthorizationModel(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadAuthorizationModelRequest) bool {
+				return req.StoreId == "store-123" && req.Id == "model-123"
+			})).Return(&openfgav1.ReadAuthorizationModelResponse{
+				AuthorizationModel: &openfgav1.AuthorizationModel{
+					Id: "model-123",
+					TypeDefinitions: []*openfgav1.TypeDefinition{
+						{Type: "core_platform-mesh_io_account", Relations: tt.relations},
+					},
+				},
+			}, nil)
+
+			missing, err := service.MissingRoleRelations(ctx, "core.platform-mesh.io", "Account", []string{"owner", "member"})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, missing)
+		})
+	}
+}
+
+func TestService_MissingRoleRelations_UnknownType(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, _ := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	client.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelResponse{
+		AuthorizationModel: &openfgav1.AuthorizationModel{Id: "model-123"},
+	}, nil)
+
+	missing, err := service.MissingRoleRelations(ctx, "apps", "Deployment", []string{"owner"})
+
+	assert.Error(t, err)
+	assert.Nil(t, missing)
+	assert.Contains(t, err.Error(), "type apps_deployment is not defined in authorization model model-123")
+}
+
+func TestService_ValidateModel(t *testing.T) {
+	tests := []struct {
+		name                string
+		deploymentRelations map[string]*openfgav1.Userset
+		expectedError       string
+	}{
+		{
+			name:                "complete model",
+			deploymentRelations: map[string]*openfgav1.Userset{"owner": {}, "member": {}},
+		},
+		{
+			name:                "model missing a role",
+			deploymentRelations: map[string]*openfgav1.Userset{"owner": {}},
+			expectedError:       "test-org: apps/Deployment misses member",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, client := createTestService(t)
+			expectPreviewStore(client)
+
+			client.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelResponse{
+				AuthorizationModel: &openfgav1.AuthorizationModel{
+					Id: "model-123",
+					TypeDefinitions: []*openfgav1.TypeDefinition{
+						{Type: "core_platform-mesh_io_account", Relations: map[string]*openfgav1.Userset{"owner": {}, "member": {}}},
+						{Type: "apps_deployment", Relations: tt.deploymentRelations},
+					},
+				},
+			}, nil)
+
+			err := service.ValidateModel(context.Background(), []string{"test-org"})
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestService_ValidateModel_ReadError(t *testing.T) {
+	service, client := createTestService(t)
+	expectPreviewStore(client)
+
+	client.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	err := service.ValidateModel(context.Background(), []string{"test-org"})
+
+	assert.ErrorContains(t, err, "failed to validate authorization model of organization test-org")
+}
+
+func TestService_Permissions_UnknownType(t *testing.T) {
+	service, client := createTestService(t)
+
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant:        "test-tenant",
+		OrganizationName: "test-org",
+	})
+	ctx = appcontext.SetAuthorizationModelId(ctx, "pinned-model")
+
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	client.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelResponse{
+		AuthorizationModel: &openfgav1.AuthorizationModel{Id: "pinned-model"},
+	}, nil)
+
+	permissions, err := service.Permissions(ctx, graph.ResourceContext{Group: "apps", Kind: "Deployment"})
+
+	assert.Error(t, err)
+	assert.Nil(t, permissions)
+	assert.Contains(t, err.Error(), "type apps_deployment is not defined in authorization model pinned-model")
+}
+
+func TestService_Permissions_ReadModelError(t *testing.T) {
+	service, client := createTestService(t)
+
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant:        "test-tenant",
+		OrganizationName: "test-org",
+	})
+	ctx = appcontext.SetAuthorizationModelId(ctx, "pinned-model")
+
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	client.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	permissions, err := service.Permissions(ctx, graph.ResourceContext{Group: "apps", Kind: "Deployment"})
+
+	assert.Error(t, err)
+	assert.Nil(t, permissions)
+	assert.Contains(t, err.Error(), "failed to read authorization model pinned-model")
+}
+
+func createPriorityTestService(t *testing.T) (*Service, *fgamocks.OpenFGAServiceClient) {
+	rolesFile := filepath.Join(t.TempDir(), "roles.yaml")
+	require.NoError(t, os.WriteFile(rolesFile, []byte(`roles:
+  - groupResource: core.platform-mesh.io/Account
+    roles:
+      - id: member
+        displayName: Member
+      - id: owner
+        displayName: Owner
+        priority: 10
+      - id: admin
+        displayName: Admin
+        priority: 20`), 0o600))
+	rolesRetriever, err := roles.NewFileBasedRolesRetriever(rolesFile)
+	require.NoError(t, err)
+
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	return NewWithRolesRetriever(client, createTestConfig(), rolesRetriever), client
+}
+
+func TestService_OwnerRole(t *testing.T) {
+	service, _ := createPriorityTestService(t)
+
+	ownerRole, err := service.OwnerRole(graph.ResourceContext{Group: "core.platform-mesh.io", Kind: "Account"})
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", ownerRole)
+
+	// Group resources without roles fall back to the owner role
+	ownerRole, err = service.OwnerRole(graph.ResourceContext{Group: "example.io", Kind: "Unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", ownerRole)
+}
+
+func TestService_ConvertToGraphUserRoles_OrderedByPriority(t *testing.T) {
+	service, _ := createPriorityTestService(t)
+
+	userRoles := service.convertToGraphUserRoles(graph.ResourceContext{Group: "core.platform-mesh.io", Kind: "Account"}, UserIDToRoles{
+		"user@example.com": {"member", "admin", "owner"},
+	})
+
+	require.Len(t, userRoles, 1)
+	var roleIDs []string
+	for _, role := range userRoles[0].Roles {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	assert.Equal(t, []string{"admin", "owner", "member"}, roleIDs)
+}
+
 func TestApplyRoleFilter_WithFilters(t *testing.T) {
 	// Create a logger for testing
 	log, _ := logger.New(logger.DefaultConfig())
@@ -392,12 +703,23 @@ func TestService_AssignRolesToUsers_Success(t *testing.T) {
 	}
 	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
 
-	// Mock Write calls for each role assignment (now writes 2 separate calls per role)
-	// For 2 roles (owner, member), we expect 4 Write calls total (2 per role)
+	// One assignee write per role
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return req.StoreId == storeID &&
+			len(req.Writes.TupleKeys) == 1 &&
+			req.Writes.TupleKeys[0].Relation == "assignee"
+	})).Return(&openfgav1.WriteResponse{}, nil).Times(2)
+	// The bindings of both roles are written with a single request
 	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		keys := req.Writes.TupleKeys
 		return req.StoreId == storeID &&
-			len(req.Writes.TupleKeys) == 1
-	})).Return(&openfgav1.WriteResponse{}, nil).Times(4)
+			len(keys) == 2 &&
+			keys[0].User == "role:core_platform-mesh_io_account/cluster-123/test-account/owner#assignee" &&
+			keys[0].Relation == "owner" &&
+			keys[0].Object == "core_platform-mesh_io_account:cluster-123/default/test-account" &&
+			keys[1].User == "role:core_platform-mesh_io_account/cluster-123/test-account/member#assignee" &&
+			keys[1].Relation == "member"
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
 
 	// Set cluster ID in context since it's now retrieved from context instead of accountinfo
 	ctx = appcontext.SetClusterId(ctx, ai.Spec.Account.GeneratedClusterId)
@@ -411,6 +733,39 @@ func TestService_AssignRolesToUsers_Success(t *testing.T) {
 	assert.Empty(t, result.Errors)
 }
 
+func TestService_AssignRolesToUsers_ExistingBinding(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	changes := []*graph.UserRoleChange{
+		{UserID: "user1@example.com", Roles: []string{"owner", "member"}},
+		{UserID: "user2@example.com", Roles: []string{"member"}},
+	}
+
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 1 && req.Writes.TupleKeys[0].Relation == "assignee"
+	})).Return(&openfgav1.WriteResponse{}, nil).Times(3)
+	// The member role is bound already, so the batched binding write falls back to single writes
+	duplicate := status.Error(codes.Code(openfgav1.ErrorCode_write_failed_due_to_invalid_input), "tuple already exists")
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 2
+	})).Return(nil, duplicate).Once()
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 1 && req.Writes.TupleKeys[0].Relation == "owner"
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 1 && req.Writes.TupleKeys[0].Relation == "member"
+	})).Return(nil, duplicate).Once()
+
+	result, err := service.AssignRolesToUsers(ctx, rCtx, changes, nil)
+
+	assert.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, 4, result.AssignedCount)
+	assert.Empty(t, result.Errors)
+}
+
 func TestService_AssignRolesToUsers_InvalidRole(t *testing.T) {
 	service, client := createTestService(t)
 
@@ -461,7 +816,7 @@ func TestService_AssignRolesToUsers_InvalidRole(t *testing.T) {
 	}
 	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
 
-	// Mock Write calls for owner role only (admin should be rejected) - now writes 2 separate calls per role
+	// Mock Write calls for owner role only (admin should be rejected)
 	// First call: assignee tuple
 	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
 		return req.StoreId == storeID &&
@@ -470,7 +825,7 @@ func TestService_AssignRolesToUsers_InvalidRole(t *testing.T) {
 			req.Writes.TupleKeys[0].Object == "role:core_platform-mesh_io_account/cluster-123/test-account/owner" &&
 			req.Writes.TupleKeys[0].Relation == "assignee"
 	})).Return(&openfgav1.WriteResponse{}, nil).Once()
-	// Second call: role tuple
+	// Second call: binding of the owner role
 	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
 		return req.StoreId == storeID &&
 			len(req.Writes.TupleKeys) == 1 &&
@@ -538,24 +893,17 @@ func TestService_RemoveRole_Success(t *testing.T) {
 	}
 	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
 
-	// Mock Read call to check if tuple exists - returns tuple (role is assigned)
-	readResponse := &openfgav1.ReadResponse{
-		Tuples: []*openfgav1.Tuple{
-			{
-				Key: &openfgav1.TupleKey{
-					User:     "user:user1@example.com",
-					Relation: "assignee",
-					Object:   "role:core_platform-mesh_io_account/cluster-123/test-account/owner",
-				},
-			},
-		},
-	}
-	client.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+	client.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}, nil)
+
+	// Mock Check call for the role assignment - the user holds the role
+	client.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
 		return req.StoreId == storeID &&
 			req.TupleKey.User == "user:user1@example.com" &&
 			req.TupleKey.Object == "role:core_platform-mesh_io_account/cluster-123/test-account/owner" &&
 			req.TupleKey.Relation == "assignee"
-	})).Return(readResponse, nil).Once()
+	})).Return(&openfgav1.CheckResponse{Allowed: true}, nil).Once()
 
 	// Mock Write call for deletion
 	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
@@ -627,16 +975,17 @@ func TestService_RemoveRole_RoleNotAssigned(t *testing.T) {
 	}
 	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(listStoresResponse, nil)
 
-	// Mock Read call to check if tuple exists - returns empty (role is not assigned)
-	readResponse := &openfgav1.ReadResponse{
-		Tuples: []*openfgav1.Tuple{}, // Empty - no tuples found
-	}
-	client.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+	client.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}, nil)
+
+	// Mock Check call for the role assignment - the user doesn't hold the role
+	client.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
 		return req.StoreId == storeID &&
 			req.TupleKey.User == "user:user1@example.com" &&
 			req.TupleKey.Object == "role:core_platform-mesh_io_account/cluster-123/test-account/member" &&
 			req.TupleKey.Relation == "assignee"
-	})).Return(readResponse, nil).Once()
+	})).Return(&openfgav1.CheckResponse{Allowed: false}, nil).Once()
 
 	// No Write call should be made since the role wasn't assigned
 
@@ -651,3 +1000,71 @@ func TestService_RemoveRole_RoleNotAssigned(t *testing.T) {
 	assert.False(t, result.WasAssigned) // But role wasn't assigned
 	assert.Nil(t, result.Error)
 }
+
+func TestService_RemoveRole_CheckError(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	client.EXPECT().Check(mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+
+	result, err := service.RemoveRole(ctx, rCtx, graph.RemoveRoleInput{UserID: "user1@example.com", Role: "member"})
+
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.False(t, result.WasAssigned)
+	require.NotNil(t, result.Error)
+	assert.Contains(t, *result.Error, "failed to check role assignment")
+}
+
+func TestService_ListUsersWithEffectiveRoles(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	owner := &openfgav1.User{User: &openfgav1.User_Object{Object: &openfgav1.Object{Type: "user", Id: "owner@example.com"}}}
+	member := &openfgav1.User{User: &openfgav1.User_Object{Object: &openfgav1.Object{Type: "user", Id: "member@example.com"}}}
+
+	// Explicit assignments are read from the role objects
+	client.EXPECT().ListUsers(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListUsersRequest) bool {
+		return req.Object.Type == "role" && req.Relation == "assignee" &&
+			req.Object.Id == "core_platform-mesh_io_account/cluster-123/test-account/owner"
+	})).Return(&openfgav1.ListUsersResponse{Users: []*openfgav1.User{owner}}, nil).Once()
+	client.EXPECT().ListUsers(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListUsersRequest) bool {
+		return req.Object.Type == "role" && req.Relation == "assignee" &&
+			req.Object.Id == "core_platform-mesh_io_account/cluster-123/test-account/member"
+	})).Return(&openfgav1.ListUsersResponse{Users: []*openfgav1.User{member}}, nil).Once()
+
+	// Effective roles are resolved on the resource, where the model grants member to owners
+	client.EXPECT().ListUsers(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListUsersRequest) bool {
+		return req.Object.Type == "core_platform-mesh_io_account" && req.Relation == "owner" &&
+			req.Object.Id == "cluster-123/test-account"
+	})).Return(&openfgav1.ListUsersResponse{Users: []*openfgav1.User{owner}}, nil).Once()
+	client.EXPECT().ListUsers(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListUsersRequest) bool {
+		return req.Object.Type == "core_platform-mesh_io_account" && req.Relation == "member" &&
+			req.Object.Id == "cluster-123/test-account"
+	})).Return(&openfgav1.ListUsersResponse{Users: []*openfgav1.User{owner, member}}, nil).Once()
+
+	roleIDs := func(userRoles []*graph.UserRoles, email string) []string {
+		var ids []string
+		for _, ur := range userRoles {
+			if ur.User.Email != email {
+				continue
+			}
+			for _, role := range ur.Roles {
+				ids = append(ids, role.ID)
+			}
+		}
+		return ids
+	}
+
+	explicit, err := service.ListUsers(ctx, rCtx, nil)
+	require.NoError(t, err)
+	effective, err := service.ListUsersWithEffectiveRoles(ctx, rCtx, nil)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"owner"}, roleIDs(explicit, "owner@example.com"))
+	assert.ElementsMatch(t, []string{"owner", "member"}, roleIDs(effective, "owner@example.com"))
+	assert.ElementsMatch(t, []string{"member"}, roleIDs(explicit, "member@example.com"))
+	assert.ElementsMatch(t, []string{"member"}, roleIDs(effective, "member@example.com"))
+}
@@ -0,0 +1,119 @@
+package fga
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/fga/util"
+	"github.com/platform-mesh/golang-commons/logger"
+	"go.opentelemetry.io/otel"
+
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
+	"github.com/platform-mesh/iam-service/pkg/fga/tuples"
+	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/platform-mesh/iam-service/pkg/roles"
+)
+
+// RoleAssignmentsAt reconstructs the users with explicit role assignments on the resource in rctx as they were
+// at the given time, by replaying the changelog of the store's role tuples up to and including that time.
+// The roles of each user are ordered by rank. Changes older than the store's changelog retention are lost,
+// so the result is only as complete as the changelog OpenFGA keeps.
+func (s *Service) RoleAssignmentsAt(ctx context.Context, rctx graph.ResourceContext, at time.Time) ([]*graph.UserRoles, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	log = log.MustChildLoggerWithAttributes("group", rctx.Group, "kind", rctx.Kind, "resource", rctx.Resource.Name)
+	ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "fga.RoleAssignmentsAt")
+	defer span.End()
+
+	clusterId, err := appcontext.GetClusterId(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster ID from context")
+	}
+
+	kctx, err := appcontext.GetKCPContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kcp user context")
+	}
+
+	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
+	}
+
+	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
+	if err != nil { // coverage-ignore
+		return nil, errors.Wrap(err, "failed to get role definitions for group resource %s/%s", rctx.Group, rctx.Kind)
+	}
+	availableRoles := roles.GetAvailableRoleIDs(roleDefinitions)
+
+	// Maps the role objects of the resource to their role
+	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+	roleObjects := map[string]string{}
+	for _, role := range availableRoles {
+		roleObjects[tuples.RoleObject(fgaTypeName, clusterId, rctx.Resource.Name, role)] = role
+	}
+
+	// Replay the changes in order, tracking the set of roles of each user
+	assigned := map[string]map[string]bool{}
+	var continuationToken string
+	var replayed int
+replay:
+	for {
+		resp, err := s.client.ReadChanges(ctx, &openfgav1.ReadChangesRequest{
+			StoreId:           storeID,
+			Type:              tuples.RoleType,
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read changes of store %s", storeID)
+		}
+
+		for _, change := range resp.GetChanges() {
+			// Changes are returned in ascending order, nothing after this one is relevant
+			if change.GetTimestamp().AsTime().After(at) {
+				break replay
+			}
+
+			key := change.GetTupleKey()
+			role, ok := roleObjects[key.GetObject()]
+			if !ok || key.GetRelation() != tuples.AssigneeRelation {
+				continue
+			}
+			userID, found := strings.CutPrefix(key.GetUser(), tuples.UserType+":")
+			if !found {
+				continue
+			}
+
+			switch change.GetOperation() {
+			case openfgav1.TupleOperation_TUPLE_OPERATION_WRITE:
+				if assigned[userID] == nil {
+					assigned[userID] = map[string]bool{}
+				}
+				assigned[userID][role] = true
+			case openfgav1.TupleOperation_TUPLE_OPERATION_DELETE:
+				delete(assigned[userID], role)
+			}
+			replayed++
+		}
+
+		// The token of the last page is returned again without any changes
+		if len(resp.GetChanges()) == 0 || resp.GetContinuationToken() == "" {
+			break
+		}
+		continuationToken = resp.GetContinuationToken()
+	}
+
+	result := UserIDToRoles{}
+	for userID, userRoles := range assigned {
+		for _, role := range availableRoles {
+			if userRoles[role] {
+				result[userID] = append(result[userID], role)
+			}
+		}
+	}
+	log.Debug().Int("changes", replayed).Int("users", len(result)).Time("at", at).Msg("Replayed role assignment changes")
+
+	return s.convertToGraphUserRoles(rctx, result), nil
+}
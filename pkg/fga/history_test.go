@@ -0,0 +1,130 @@
+package fga
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func roleChange(operation openfgav1.TupleOperation, day int, user, relation, object string) *openfgav1.TupleChange {
+	return &openfgav1.TupleChange{
+		TupleKey:  &openfgav1.TupleKey{User: user, Relation: relation, Object: object},
+		Operation: operation,
+		Timestamp: timestamppb.New(time.Date(2026, time.January, day, 12, 0, 0, 0, time.UTC)),
+	}
+}
+
+func TestService_RoleAssignmentsAt(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	const (
+		write   = openfgav1.TupleOperation_TUPLE_OPERATION_WRITE
+		remove  = openfgav1.TupleOperation_TUPLE_OPERATION_DELETE
+		owner   = "role:core_platform-mesh_io_account/cluster-123/test-account/owner"
+		member  = "role:core_platform-mesh_io_account/cluster-123/test-account/member"
+		other   = "role:core_platform-mesh_io_account/cluster-123/other-account/owner"
+		account = "core_platform-mesh_io_account:cluster-123/test-account"
+	)
+	pages := map[string]*openfgav1.ReadChangesResponse{
+		"": {
+			Changes: []*openfgav1.TupleChange{
+				roleChange(write, 1, "user:alice@example.com", "assignee", owner),
+				roleChange(write, 1, owner+"#assignee", "owner", account),
+				roleChange(write, 2, "user:bob@example.com", "assignee", member),
+				// Assignments on other resources are ignored
+				roleChange(write, 2, "user:carol@example.com", "assignee", other),
+			},
+			ContinuationToken: "page-2",
+		},
+		"page-2": {
+			Changes: []*openfgav1.TupleChange{
+				roleChange(write, 3, "user:alice@example.com", "assignee", member),
+				roleChange(remove, 4, "user:alice@example.com", "assignee", owner),
+				roleChange(remove, 5, "user:bob@example.com", "assignee", member),
+			},
+			ContinuationToken: "page-3",
+		},
+		"page-3": {ContinuationToken: "page-3"},
+	}
+	client.EXPECT().ReadChanges(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, req *openfgav1.ReadChangesRequest, _ ...grpc.CallOption) (*openfgav1.ReadChangesResponse, error) {
+		assert.Equal(t, "store-123", req.StoreId)
+		assert.Equal(t, "role", req.Type)
+		return pages[req.ContinuationToken], nil
+	})
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected UserIDToRoles
+	}{
+		{
+			name:     "before any change",
+			at:       time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC),
+			expected: UserIDToRoles{},
+		},
+		{
+			name:     "at the time of a change",
+			at:       time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC),
+			expected: UserIDToRoles{"alice@example.com": {"owner"}},
+		},
+		{
+			name: "with several roles",
+			at:   time.Date(2026, time.January, 3, 18, 0, 0, 0, time.UTC),
+			expected: UserIDToRoles{
+				"alice@example.com": {"owner", "member"},
+				"bob@example.com":   {"member"},
+			},
+		},
+		{
+			name: "after a removal",
+			at:   time.Date(2026, time.January, 4, 18, 0, 0, 0, time.UTC),
+			expected: UserIDToRoles{
+				"alice@example.com": {"member"},
+				"bob@example.com":   {"member"},
+			},
+		},
+		{
+			name:     "after all changes",
+			at:       time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+			expected: UserIDToRoles{"alice@example.com": {"member"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.RoleAssignmentsAt(ctx, rCtx, tt.at)
+
+			require.NoError(t, err)
+			roleIDs := UserIDToRoles{}
+			for _, userRoles := range result {
+				for _, role := range userRoles.Roles {
+					roleIDs[userRoles.User.Email] = append(roleIDs[userRoles.User.Email], role.ID)
+				}
+			}
+			assert.Equal(t, tt.expected, roleIDs)
+		})
+	}
+}
+
+func TestService_RoleAssignmentsAt_ReadChangesError(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	client.EXPECT().ReadChanges(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	result, err := service.RoleAssignmentsAt(ctx, rCtx, time.Now())
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "failed to read changes of store store-123")
+}
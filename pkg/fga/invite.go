@@ -6,19 +6,31 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/mail"
+	"slices"
+	"strings"
+	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	pmcontext "github.com/platform-mesh/golang-commons/context"
 	"github.com/platform-mesh/golang-commons/errors"
-	"github.com/platform-mesh/golang-commons/fga/util"
 	"github.com/platform-mesh/golang-commons/logger"
 	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/platform-mesh/iam-service/pkg/fga/tuples"
 	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/platform-mesh/iam-service/pkg/redact"
 	"github.com/platform-mesh/iam-service/pkg/roles"
 )
 
+const (
+	// invitedByAnnotation records the email of the user who sent the invite
+	invitedByAnnotation = "platform-mesh.io/invited-by"
+	// invitedAtAnnotation records when the invite was sent, in RFC 3339 format
+	invitedAtAnnotation = "platform-mesh.io/invited-at"
+)
+
 // emailToLabelValue converts an email address to a valid Kubernetes label value
 // by creating a SHA-1 hash. This ensures the value meets Kubernetes label requirements:
 // - 63 characters or less
@@ -28,14 +40,52 @@ func emailToLabelValue(email string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// checkInviteDomain returns an error if the invite policy doesn't allow inviting the email's domain.
+// Blocked domains take precedence over allowed ones. Without a policy every email can be invited.
+func (s *Service) checkInviteDomain(email string) error {
+	if len(s.invitePolicy.AllowedEmailDomains) == 0 && len(s.invitePolicy.BlockedEmailDomains) == 0 {
+		return nil
+	}
+
+	_, domain, found := strings.Cut(strings.ToLower(email), "@")
+	if !found || domain == "" {
+		return errors.New("email %s has no domain", redact.Email(email))
+	}
+	if matchesDomain(s.invitePolicy.BlockedEmailDomains, domain) {
+		return errors.New("email domain %s is blocked", domain)
+	}
+	if len(s.invitePolicy.AllowedEmailDomains) > 0 && !matchesDomain(s.invitePolicy.AllowedEmailDomains, domain) {
+		return errors.New("email domain %s is not allowed", domain)
+	}
+	return nil
+}
+
+// matchesDomain reports whether the domain matches one of the patterns. A pattern matches
+// the domain exactly, or with a "*." prefix any of its subdomains.
+func matchesDomain(patterns []string, domain string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if parent, found := strings.CutPrefix(pattern, "*."); found {
+			if strings.HasSuffix(domain, "."+parent) {
+				return true
+			}
+			continue
+		}
+		if domain == pattern {
+			return true
+		}
+	}
+	return false
+}
+
 // checkAndInviteUser checks if a user exists in the IDM system and creates an Invite if not
 func (s *Service) checkAndInviteUser(ctx context.Context, userEmail string, rctx graph.ResourceContext) error {
-	log := logger.LoadLoggerFromContext(ctx).MustChildLoggerWithAttributes("email", sanitizeUserID(userEmail))
+	log := logger.LoadLoggerFromContext(ctx).MustChildLoggerWithAttributes("email", redact.Email(userEmail))
 
 	// Check if user exists in IDM system
 	usr, err := s.idmChecker.UserByMail(ctx, userEmail)
 	if err != nil {
-		return errors.Wrap(err, "failed to check if user %s exists in IDM system", sanitizeUserID(userEmail))
+		return errors.Wrap(err, "failed to check if user %s exists in IDM system", redact.Email(userEmail))
 	}
 
 	if usr != nil {
@@ -57,7 +107,7 @@ func (s *Service) checkAndInviteUser(ctx context.Context, userEmail string, rctx
 	}
 
 	if err := s.createInviteIfNotExists(ctx, wsClient, userEmail); err != nil {
-		return errors.Wrap(err, "failed to create Invite for user %s", sanitizeUserID(userEmail))
+		return errors.Wrap(err, "failed to create Invite for user %s", redact.Email(userEmail))
 	}
 
 	return nil
@@ -67,10 +117,10 @@ func (s *Service) checkAndInviteUser(ctx context.Context, userEmail string, rctx
 func (s *Service) createInviteIfNotExists(ctx context.Context, wsClient client.Client, userEmail string) error {
 	// Validate email format
 	if _, err := mail.ParseAddress(userEmail); err != nil {
-		return errors.Wrap(err, "invalid email format for %s", sanitizeUserID(userEmail))
+		return errors.Wrap(err, "invalid email format for %s", redact.Email(userEmail))
 	}
 
-	log := logger.LoadLoggerFromContext(ctx).MustChildLoggerWithAttributes("email", sanitizeUserID(userEmail))
+	log := logger.LoadLoggerFromContext(ctx).MustChildLoggerWithAttributes("email", redact.Email(userEmail))
 
 	// Check if an Invite already exists for this email using label selector
 	// Use hash of email as label value since email contains invalid characters (@, .)
@@ -80,7 +130,7 @@ func (s *Service) createInviteIfNotExists(ctx context.Context, wsClient client.C
 		"platform-mesh.io/invite-email-hash": emailHash,
 	}
 	if err := wsClient.List(ctx, inviteList, labelSelector); err != nil { // coverage-ignore
-		return errors.Wrap(err, "failed to list existing Invites for %s", sanitizeUserID(userEmail))
+		return errors.Wrap(err, "failed to list existing Invites for %s", redact.Email(userEmail))
 	}
 
 	// If invite already exists, return early
@@ -89,13 +139,20 @@ func (s *Service) createInviteIfNotExists(ctx context.Context, wsClient client.C
 		return nil
 	}
 
-	// Create new Invite with label
+	// Create new Invite with label, recording who sent it and when
+	annotations := map[string]string{
+		invitedAtAnnotation: time.Now().UTC().Format(time.RFC3339),
+	}
+	if webToken, err := pmcontext.GetWebTokenFromContext(ctx); err == nil && webToken.Mail != "" {
+		annotations[invitedByAnnotation] = webToken.Mail
+	}
 	invite := &securityv1alpha1.Invite{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: "invite-",
 			Labels: map[string]string{
 				"platform-mesh.io/invite-email-hash": emailHash,
 			},
+			Annotations: annotations,
 		},
 		Spec: securityv1alpha1.InviteSpec{
 			Email: userEmail,
@@ -103,25 +160,35 @@ func (s *Service) createInviteIfNotExists(ctx context.Context, wsClient client.C
 	}
 
 	if err := wsClient.Create(ctx, invite); err != nil { // coverage-ignore
-		return errors.Wrap(err, "failed to create Invite resource for %s", sanitizeUserID(userEmail))
+		return errors.Wrap(err, "failed to create Invite resource for %s", redact.Email(userEmail))
 	}
 
 	log.Info().Str("inviteName", invite.Name).Msg("Successfully created Invite resource")
 	return nil
 }
 
-// processInvites processes invite requests: checks if users exist, creates Invite resources if not, and assigns roles
-func (s *Service) processInvites(ctx context.Context, rctx graph.ResourceContext, invites []*graph.InviteInput, storeID, fgaTypeName, clusterId string, log *logger.Logger) (int, []string) {
+// processInvites processes invite requests: checks if users exist, creates Invite resources if not, and assigns roles.
+// The assigned roles are returned to be bound to the resource.
+func (s *Service) processInvites(ctx context.Context, rctx graph.ResourceContext, invites []*graph.InviteInput, storeID, fgaTypeName, clusterId string, log *logger.Logger) (int, []string, []string) {
 	var inviteErrors []string
 	var assignedCount int
+	var assignedRoles []string
 
 	for _, invite := range invites {
-		inviteLog := log.MustChildLoggerWithAttributes("email", sanitizeUserID(invite.Email))
+		inviteLog := log.MustChildLoggerWithAttributes("email", redact.Email(invite.Email))
 		inviteLog.Debug().Interface("roles", invite.Roles).Msg("Processing invite")
 
+		// Rejected invites neither create an Invite nor assign any roles
+		if err := s.checkInviteDomain(invite.Email); err != nil {
+			errMsg := fmt.Sprintf("cannot invite user '%s': %v", redact.Email(invite.Email), err)
+			inviteErrors = append(inviteErrors, errMsg)
+			inviteLog.Warn().Err(err).Msg("Invite rejected by email domain policy")
+			continue
+		}
+
 		// Check if user exists in IDM system and create Invite if not
 		if err := s.checkAndInviteUser(ctx, invite.Email, rctx); err != nil {
-			errMsg := fmt.Sprintf("failed to create invite for user '%s': %v", sanitizeUserID(invite.Email), err)
+			errMsg := fmt.Sprintf("failed to create invite for user '%s': %v", redact.Email(invite.Email), err)
 			inviteErrors = append(inviteErrors, errMsg)
 			inviteLog.Warn().Err(err).Msg("Failed to create Invite for user, continuing with role assignment")
 		}
@@ -140,77 +207,73 @@ func (s *Service) processInvites(ctx context.Context, rctx graph.ResourceContext
 		for _, role := range invite.Roles {
 			roleLog := inviteLog.MustChildLoggerWithAttributes("role", role)
 			if !containsString(availableRoles, role) {
-				errMsg := fmt.Sprintf("role '%s' is not allowed for user '%s'. Only roles %v are permitted", role, sanitizeUserID(invite.Email), availableRoles)
+				errMsg := fmt.Sprintf("role '%s' is not allowed for user '%s'. Only roles %v are permitted", role, redact.Email(invite.Email), availableRoles)
 				inviteErrors = append(inviteErrors, errMsg)
 				roleLog.Warn().Interface("availableRoles", availableRoles).Msg("Invalid role assignment attempted")
 				continue
 			}
 
-			// Create the role tuple and assign role tuple for this user-role combination
+			// Create the role assignment tuple for this user-role combination
 			count, errs := s.assignRoleToUser(ctx, invite.Email, role, rctx, storeID, fgaTypeName, clusterId, roleLog)
 			assignedCount += count
 			inviteErrors = append(inviteErrors, errs...)
+			if !slices.Contains(assignedRoles, role) {
+				assignedRoles = append(assignedRoles, role)
+			}
 		}
 	}
 
-	return assignedCount, inviteErrors
+	return assignedCount, inviteErrors, assignedRoles
 }
 
-// assignRoleToUser assigns a single role to a user by creating both the role assignment tuple and the permission tuple
+// assignRoleToUser assigns a single role to a user by creating the role assignment tuple.
+// The binding of the role to the resource is written by bindRoles.
 func (s *Service) assignRoleToUser(ctx context.Context, userEmail, role string, rctx graph.ResourceContext, storeID, fgaTypeName, clusterId string, log *logger.Logger) (int, []string) {
-	var errors []string
-	var assignedCount int
-
-	// Create the role assignment tuple (user -> role)
 	roleTuple := &openfgav1.TupleKey{
-		User:     fmt.Sprintf("user:%s", userEmail),
-		Relation: "assignee",
-		Object: fmt.Sprintf("role:%s/%s/%s/%s",
-			fgaTypeName,
-			clusterId,
-			rctx.Resource.Name,
-			role),
-	}
-
-	// Create the permission tuple (role -> resource)
-	targetFGATypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
-	targetObject := fmt.Sprintf("%s:%s/%s", targetFGATypeName, clusterId, rctx.Resource.Name)
-	if rctx.Resource.Namespace != nil {
-		targetObject = fmt.Sprintf("%s:%s/%s/%s", targetFGATypeName, clusterId, *rctx.Resource.Namespace, rctx.Resource.Name)
-	}
-	assignRoleTuple := &openfgav1.TupleKey{
-		User: fmt.Sprintf("role:%s/%s/%s/%s#assignee",
-			fgaTypeName,
-			clusterId,
-			rctx.Resource.Name,
-			role),
-		Relation: role,
-		Object:   targetObject,
-	}
-
-	// Write both tuples
-	for _, write := range []*openfgav1.TupleKey{roleTuple, assignRoleTuple} {
-		writeReq := &openfgav1.WriteRequest{
-			StoreId: storeID,
-			Writes: &openfgav1.WriteRequestWrites{
-				TupleKeys: []*openfgav1.TupleKey{write},
-			},
-		}
+		User:     tuples.User(userEmail),
+		Relation: tuples.AssigneeRelation,
+		Object:   tuples.RoleObject(fgaTypeName, clusterId, rctx.Resource.Name, role),
+	}
 
-		_, err := s.client.Write(ctx, writeReq)
-		if err != nil {
-			if isDuplicateWriteError(err) {
-				log.Info().Str("relation", write.Relation).Str("object", write.Object).Msg("Tuple already exists, skipping duplicate")
-			} else { // coverage-ignore
-				errMsg := fmt.Sprintf("failed to assign role '%s' to user '%s': %v", role, sanitizeUserID(userEmail), err)
-				errors = append(errors, errMsg)
-				log.Error().Err(err).Msg("Failed to write tuple to FGA")
-			}
-		} else {
-			assignedCount++
-			log.Info().Msg("Successfully assigned role to user")
+	_, err := s.client.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.WriteRequestWrites{
+			TupleKeys: []*openfgav1.TupleKey{roleTuple},
+		},
+	})
+	if isDuplicateWriteError(err) {
+		log.Info().Str("relation", roleTuple.Relation).Str("object", roleTuple.Object).Msg("Tuple already exists, skipping duplicate")
+		return 0, nil
+	}
+	if err != nil { // coverage-ignore
+		log.Error().Err(err).Msg("Failed to write tuple to FGA")
+		return 0, []string{fmt.Sprintf("failed to assign role '%s' to user '%s': %v", role, redact.Email(userEmail), err)}
+	}
+
+	log.Info().Msg("Successfully assigned role to user")
+	return 1, nil
+}
+
+// bindRoles binds the roles to the resource with a single write, so that their assignees get the
+// permissions of the roles on the resource. Bindings that already exist are skipped.
+func (s *Service) bindRoles(ctx context.Context, rctx graph.ResourceContext, roleIDs []string, storeID, fgaTypeName, clusterId string, log *logger.Logger) (int, []string) {
+	if len(roleIDs) == 0 {
+		return 0, nil
+	}
+
+	bindings := make([]*openfgav1.TupleKey, len(roleIDs))
+	for i, role := range roleIDs {
+		bindings[i] = &openfgav1.TupleKey{
+			User:     tuples.RoleAssigneeUserset(fgaTypeName, clusterId, rctx.Resource.Name, role),
+			Relation: role,
+			Object:   tuples.ResourceObject(fgaTypeName, clusterId, rctx.Resource.Namespace, rctx.Resource.Name),
 		}
 	}
 
-	return assignedCount, errors
+	written, err := s.writeIgnoringDuplicates(ctx, storeID, bindings, log)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to write role bindings to FGA")
+		return written, []string{fmt.Sprintf("failed to bind roles %v to resource '%s': %v", roleIDs, rctx.Resource.Name, err)}
+	}
+	return written, nil
 }
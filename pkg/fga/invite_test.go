@@ -3,9 +3,12 @@ package fga
 import (
 	"context"
 	"testing"
+	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	accountsv1alpha1 "github.com/platform-mesh/account-operator/api/v1alpha1"
+	"github.com/platform-mesh/golang-commons/context/keys"
+	"github.com/platform-mesh/golang-commons/jwt"
 	"github.com/platform-mesh/golang-commons/logger"
 	securityv1alpha1 "github.com/platform-mesh/security-operator/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
@@ -16,6 +19,7 @@ import (
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/platform-mesh/iam-service/pkg/config"
 	appcontext "github.com/platform-mesh/iam-service/pkg/context"
 	fgamocks "github.com/platform-mesh/iam-service/pkg/fga/mocks"
 	"github.com/platform-mesh/iam-service/pkg/graph"
@@ -128,7 +132,7 @@ func TestService_AssignRolesToUsers_WithInvites_UserExists(t *testing.T) {
 		Email:  "newuser@example.com",
 	}, nil).Once()
 
-	// Mock Write calls for role assignment (2 writes per role: assignee + role tuple)
+	// Mock Write calls for role assignment (assignee tuple + role binding)
 	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
 		return req.StoreId == storeID &&
 			len(req.Writes.TupleKeys) == 1
@@ -220,7 +224,7 @@ func TestService_AssignRolesToUsers_WithInvites_UserDoesNotExist(t *testing.T) {
 	// Mock workspace client creation (path is appended with resource name for Account)
 	mockWsFactory.EXPECT().New(mock.Anything, "root:org:test-account:test-account").Return(mockWsClient, nil).Once()
 
-	// Mock Write calls for role assignment (2 writes per role: assignee + role tuple)
+	// Mock Write calls for role assignment (assignee tuple + role binding)
 	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
 		return req.StoreId == storeID &&
 			len(req.Writes.TupleKeys) == 1
@@ -388,11 +392,20 @@ func TestService_AssignRolesToUsers_WithBothChangesAndInvites(t *testing.T) {
 	}, nil).Once()
 
 	// Mock Write calls for role assignments
-	// 2 writes for owner role (existing user) + 2 writes for member role (invited user) = 4 total
+	// 1 assignee write for the invited user + 1 for the existing user
 	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
 		return req.StoreId == storeID &&
-			len(req.Writes.TupleKeys) == 1
-	})).Return(&openfgav1.WriteResponse{}, nil).Times(4)
+			len(req.Writes.TupleKeys) == 1 &&
+			req.Writes.TupleKeys[0].Relation == "assignee"
+	})).Return(&openfgav1.WriteResponse{}, nil).Times(2)
+	// Bindings of the member and owner role in a single write, invites are processed first
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		keys := req.Writes.TupleKeys
+		return req.StoreId == storeID &&
+			len(keys) == 2 &&
+			keys[0].Relation == "member" &&
+			keys[1].Relation == "owner"
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
 
 	// Set cluster ID in context
 	ctx = appcontext.SetClusterId(ctx, ai.Spec.Account.GeneratedClusterId)
@@ -402,7 +415,7 @@ func TestService_AssignRolesToUsers_WithBothChangesAndInvites(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.True(t, result.Success)
-	assert.Equal(t, 4, result.AssignedCount) // 2 for existing user + 2 for invited user
+	assert.Equal(t, 4, result.AssignedCount) // 2 assignee tuples + 2 role bindings
 	assert.Empty(t, result.Errors)
 }
 
@@ -663,3 +676,147 @@ func TestService_AssignRolesToUsers_WithInvites_InvalidEmail(t *testing.T) {
 	assert.NotEmpty(t, result.Errors)
 	assert.Contains(t, result.Errors[0], "failed to create invite")
 }
+
+func TestService_CheckInviteDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  config.InviteConfig
+		email   string
+		wantErr string
+	}{
+		{
+			name:  "no policy",
+			email: "user@gmail.com",
+		},
+		{
+			name:   "allowed domain",
+			policy: config.InviteConfig{AllowedEmailDomains: []string{"example.com"}},
+			email:  "user@Example.com",
+		},
+		{
+			name:    "domain not allowed",
+			policy:  config.InviteConfig{AllowedEmailDomains: []string{"example.com"}},
+			email:   "user@gmail.com",
+			wantErr: "email domain gmail.com is not allowed",
+		},
+		{
+			name:    "blocked domain",
+			policy:  config.InviteConfig{BlockedEmailDomains: []string{"gmail.com"}},
+			email:   "user@gmail.com",
+			wantErr: "email domain gmail.com is blocked",
+		},
+		{
+			name:   "wildcard matches subdomain",
+			policy: config.InviteConfig{AllowedEmailDomains: []string{"*.example.com"}},
+			email:  "user@eu.corp.example.com",
+		},
+		{
+			name:    "wildcard does not match the domain itself",
+			policy:  config.InviteConfig{AllowedEmailDomains: []string{"*.example.com"}},
+			email:   "user@example.com",
+			wantErr: "email domain example.com is not allowed",
+		},
+		{
+			name:    "wildcard does not match a domain with the same suffix",
+			policy:  config.InviteConfig{AllowedEmailDomains: []string{"*.example.com"}},
+			email:   "user@badexample.com",
+			wantErr: "email domain badexample.com is not allowed",
+		},
+		{
+			name: "blocked subdomain of an allowed domain",
+			policy: config.InviteConfig{
+				AllowedEmailDomains: []string{"example.com", "*.example.com"},
+				BlockedEmailDomains: []string{"*.guest.example.com"},
+			},
+			email:   "user@partner.guest.example.com",
+			wantErr: "email domain partner.guest.example.com is blocked",
+		},
+		{
+			name:    "email without domain",
+			policy:  config.InviteConfig{BlockedEmailDomains: []string{"gmail.com"}},
+			email:   "invalid-email",
+			wantErr: "has no domain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &Service{invitePolicy: tt.policy}
+
+			err := service.checkInviteDomain(tt.email)
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_AssignRolesToUsers_WithInvites_BlockedDomain(t *testing.T) {
+	service, client := createTestService(t)
+	service.invitePolicy = config.InviteConfig{AllowedEmailDomains: []string{"example.com"}}
+
+	// Neither the IDM nor the workspace may be consulted for a rejected invite
+	service.wsClientFactory = fgamocks.NewClientFactory(t)
+	service.idmChecker = fgamocks.NewIDMUserChecker(t)
+
+	ctx, rCtx := createPreviewTestContext()
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+
+	invites := []*graph.InviteInput{{Email: "someone@gmail.com", Roles: []string{"member"}}}
+	result, err := service.AssignRolesToUsers(ctx, rCtx, nil, invites)
+
+	assert.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, 0, result.AssignedCount)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "email domain gmail.com is not allowed")
+}
+
+func TestService_CreateInviteIfNotExists_RecordsInviter(t *testing.T) {
+	service, _ := createTestService(t)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, securityv1alpha1.AddToScheme(scheme))
+	wsClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	ctx := context.WithValue(context.Background(), keys.WebTokenCtxKey, jwt.WebToken{
+		ParsedAttributes: jwt.ParsedAttributes{Mail: "inviter@example.com"},
+	})
+	before := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, service.createInviteIfNotExists(ctx, wsClient, "newuser@example.com"))
+
+	inviteList := &securityv1alpha1.InviteList{}
+	require.NoError(t, wsClient.List(ctx, inviteList))
+	require.Len(t, inviteList.Items, 1)
+
+	annotations := inviteList.Items[0].Annotations
+	assert.Equal(t, "inviter@example.com", annotations[invitedByAnnotation])
+	invitedAt, err := time.Parse(time.RFC3339, annotations[invitedAtAnnotation])
+	require.NoError(t, err)
+	assert.False(t, invitedAt.Before(before))
+}
+
+func TestService_CreateInviteIfNotExists_NoWebToken(t *testing.T) {
+	service, _ := createTestService(t)
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, securityv1alpha1.AddToScheme(scheme))
+	wsClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	ctx := context.Background()
+
+	require.NoError(t, service.createInviteIfNotExists(ctx, wsClient, "newuser@example.com"))
+
+	inviteList := &securityv1alpha1.InviteList{}
+	require.NoError(t, wsClient.List(ctx, inviteList))
+	require.Len(t, inviteList.Items, 1)
+
+	annotations := inviteList.Items[0].Annotations
+	assert.NotContains(t, annotations, invitedByAnnotation)
+	assert.NotEmpty(t, annotations[invitedAtAnnotation])
+}
@@ -0,0 +1,217 @@
+package fga
+
+import (
+	"context"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/fga/util"
+	"github.com/platform-mesh/golang-commons/logger"
+	"go.opentelemetry.io/otel"
+
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
+	"github.com/platform-mesh/iam-service/pkg/fga/tuples"
+	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/platform-mesh/iam-service/pkg/roles"
+)
+
+// defaultMaxTuplesPerWrite is the default limit of tuples OpenFGA accepts in a single write request
+const defaultMaxTuplesPerWrite = 100
+
+// MigrateRoleAssignments copies all role assignments of the resource in rctx to the target resource
+// of the same group and kind in the same cluster, including the bindings of the roles to the target.
+// Tuples that already exist on the target are skipped. With deleteSource the assignments
+// and bindings of the source resource are deleted once everything has been copied.
+func (s *Service) MigrateRoleAssignments(ctx context.Context, rctx graph.ResourceContext, target *graph.Resource, deleteSource bool) error {
+	log := logger.LoadLoggerFromContext(ctx)
+	log = log.MustChildLoggerWithAttributes("group", rctx.Group, "kind", rctx.Kind, "from", rctx.Resource.Name, "to", target.Name)
+	ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "fga.MigrateRoleAssignments")
+	defer span.End()
+
+	// Role objects are identified by the resource name only, so the same name in another namespace
+	// shares the role assignments. Deleting the source would wipe them.
+	if target.Name == rctx.Resource.Name {
+		return errors.New("cannot migrate role assignments of resource %s to itself", target.Name)
+	}
+
+	clusterId, err := appcontext.GetClusterId(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster ID from context")
+	}
+
+	kctx, err := appcontext.GetKCPContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get kcp user context")
+	}
+
+	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
+	}
+
+	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
+	if err != nil { // coverage-ignore
+		return errors.Wrap(err, "failed to get role definitions for group resource %s/%s", rctx.Group, rctx.Kind)
+	}
+
+	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+	source := rctx.Resource
+
+	var writes []*openfgav1.TupleKey
+	var deletes []*openfgav1.TupleKeyWithoutCondition
+	for _, role := range roles.GetAvailableRoleIDs(roleDefinitions) {
+		assignees, err := s.readAll(ctx, storeID, &openfgav1.ReadRequestTupleKey{
+			Relation: tuples.AssigneeRelation,
+			Object:   tuples.RoleObject(fgaTypeName, clusterId, source.Name, role),
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to read assignees of role %s on resource %s", role, source.Name)
+		}
+		if len(assignees) == 0 {
+			continue
+		}
+
+		for _, assignee := range assignees {
+			writes = append(writes, &openfgav1.TupleKey{
+				User:     assignee.Key.User,
+				Relation: tuples.AssigneeRelation,
+				Object:   tuples.RoleObject(fgaTypeName, clusterId, target.Name, role),
+			})
+			deletes = append(deletes, &openfgav1.TupleKeyWithoutCondition{
+				User:     assignee.Key.User,
+				Relation: tuples.AssigneeRelation,
+				Object:   assignee.Key.Object,
+			})
+		}
+
+		// Bind the role to the target resource, mirroring assignRoleToUser
+		writes = append(writes, &openfgav1.TupleKey{
+			User:     tuples.RoleAssigneeUserset(fgaTypeName, clusterId, target.Name, role),
+			Relation: role,
+			Object:   tuples.ResourceObject(fgaTypeName, clusterId, target.Namespace, target.Name),
+		})
+		deletes = append(deletes, &openfgav1.TupleKeyWithoutCondition{
+			User:     tuples.RoleAssigneeUserset(fgaTypeName, clusterId, source.Name, role),
+			Relation: role,
+			Object:   tuples.ResourceObject(fgaTypeName, clusterId, source.Namespace, source.Name),
+		})
+	}
+
+	if _, err := s.writeIgnoringDuplicates(ctx, storeID, writes, log); err != nil {
+		return errors.Wrap(err, "failed to write role assignments for resource %s", target.Name)
+	}
+	log.Info().Int("tuples", len(writes)).Msg("Copied role assignments")
+
+	if !deleteSource {
+		return nil
+	}
+
+	if err := s.deleteTuples(ctx, storeID, deletes); err != nil {
+		return errors.Wrap(err, "failed to delete role assignments of resource %s", source.Name)
+	}
+	log.Info().Int("tuples", len(deletes)).Msg("Deleted role assignments of source resource")
+
+	return nil
+}
+
+// readAll reads all tuples matching the key, following continuation tokens
+func (s *Service) readAll(ctx context.Context, storeID string, key *openfgav1.ReadRequestTupleKey) ([]*openfgav1.Tuple, error) {
+	var result []*openfgav1.Tuple
+	var continuationToken string
+	for {
+		res, err := s.client.Read(ctx, &openfgav1.ReadRequest{
+			StoreId:           storeID,
+			TupleKey:          key,
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, res.Tuples...)
+		if res.ContinuationToken == "" {
+			return result, nil
+		}
+		continuationToken = res.ContinuationToken
+	}
+}
+
+// writeChunkSize returns the maximum number of tuples sent in a single write request
+func (s *Service) writeChunkSize() int {
+	if s.maxTuplesPerWrite > 0 {
+		return s.maxTuplesPerWrite
+	}
+	return defaultMaxTuplesPerWrite
+}
+
+// deleteTuples deletes the tuples in as few requests as the write chunk size allows
+func (s *Service) deleteTuples(ctx context.Context, storeID string, tupleKeys []*openfgav1.TupleKeyWithoutCondition) error {
+	for chunk := range chunks(tupleKeys, s.writeChunkSize()) {
+		_, err := s.client.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Deletes: &openfgav1.WriteRequestDeletes{TupleKeys: chunk},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeIgnoringDuplicates writes the tuples in as few requests as the write chunk size allows
+// and returns the number of written tuples.
+func (s *Service) writeIgnoringDuplicates(ctx context.Context, storeID string, tupleKeys []*openfgav1.TupleKey, log *logger.Logger) (int, error) {
+	written := 0
+	for chunk := range chunks(tupleKeys, s.writeChunkSize()) {
+		n, err := s.writeChunkIgnoringDuplicates(ctx, storeID, chunk, log)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// writeChunkIgnoringDuplicates writes the tuples in a single request and returns the number of written tuples.
+// OpenFGA rejects the whole request if one of the tuples already exists, in that case the tuples are
+// written one by one skipping duplicates.
+func (s *Service) writeChunkIgnoringDuplicates(ctx context.Context, storeID string, tupleKeys []*openfgav1.TupleKey, log *logger.Logger) (int, error) {
+	_, err := s.client.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes:  &openfgav1.WriteRequestWrites{TupleKeys: tupleKeys},
+	})
+	if err == nil {
+		return len(tupleKeys), nil
+	}
+	if !isDuplicateWriteError(err) {
+		return 0, err
+	}
+
+	written := 0
+	for _, tupleKey := range tupleKeys {
+		_, err := s.client.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes:  &openfgav1.WriteRequestWrites{TupleKeys: []*openfgav1.TupleKey{tupleKey}},
+		})
+		if isDuplicateWriteError(err) {
+			log.Info().Str("relation", tupleKey.Relation).Str("object", tupleKey.Object).Msg("Tuple already exists, skipping duplicate")
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// chunks yields consecutive chunks of at most size elements
+func chunks[T any](items []T, size int) func(yield func([]T) bool) {
+	return func(yield func([]T) bool) {
+		for start := 0; start < len(items); start += size {
+			if !yield(items[start:min(start+size, len(items))]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package fga
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/utils/ptr"
+
+	fgamocks "github.com/platform-mesh/iam-service/pkg/fga/mocks"
+	"github.com/platform-mesh/iam-service/pkg/graph"
+)
+
+func expectRoleAssignees(client *fgamocks.OpenFGAServiceClient, role string, users ...string) {
+	object := "role:core_platform-mesh_io_account/cluster-123/test-account/" + role
+	res := &openfgav1.ReadResponse{}
+	for _, user := range users {
+		res.Tuples = append(res.Tuples, &openfgav1.Tuple{
+			Key: &openfgav1.TupleKey{User: user, Relation: "assignee", Object: object},
+		})
+	}
+	client.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+		return req.StoreId == "store-123" && req.GetTupleKey().GetRelation() == "assignee" && req.GetTupleKey().GetObject() == object
+	})).Return(res, nil).Once()
+}
+
+func TestService_MigrateRoleAssignments(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:owner@example.com")
+	expectRoleAssignees(client, "member", "user:member1@example.com", "user:member2@example.com")
+
+	var written []string
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return req.Writes != nil
+	})).RunAndReturn(func(_ context.Context, req *openfgav1.WriteRequest, _ ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+		for _, key := range req.Writes.TupleKeys {
+			written = append(written, key.User+" "+key.Relation+" "+key.Object)
+		}
+		return &openfgav1.WriteResponse{}, nil
+	}).Once()
+
+	var deleted []string
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return req.Deletes != nil
+	})).RunAndReturn(func(_ context.Context, req *openfgav1.WriteRequest, _ ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+		for _, key := range req.Deletes.TupleKeys {
+			deleted = append(deleted, key.User+" "+key.Relation+" "+key.Object)
+		}
+		return &openfgav1.WriteResponse{}, nil
+	}).Once()
+
+	err := service.MigrateRoleAssignments(ctx, rCtx, &graph.Resource{Name: "new-account"}, true)
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"user:owner@example.com assignee role:core_platform-mesh_io_account/cluster-123/new-account/owner",
+		"role:core_platform-mesh_io_account/cluster-123/new-account/owner#assignee owner core_platform-mesh_io_account:cluster-123/new-account",
+		"user:member1@example.com assignee role:core_platform-mesh_io_account/cluster-123/new-account/member",
+		"user:member2@example.com assignee role:core_platform-mesh_io_account/cluster-123/new-account/member",
+		"role:core_platform-mesh_io_account/cluster-123/new-account/member#assignee member core_platform-mesh_io_account:cluster-123/new-account",
+	}, written)
+	assert.ElementsMatch(t, []string{
+		"user:owner@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/owner",
+		"role:core_platform-mesh_io_account/cluster-123/test-account/owner#assignee owner core_platform-mesh_io_account:cluster-123/test-account",
+		"user:member1@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/member",
+		"user:member2@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/member",
+		"role:core_platform-mesh_io_account/cluster-123/test-account/member#assignee member core_platform-mesh_io_account:cluster-123/test-account",
+	}, deleted)
+}
+
+func TestService_MigrateRoleAssignments_KeepsSource(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:owner@example.com")
+	// Roles without assignees are not bound to the target
+	expectRoleAssignees(client, "member")
+
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return req.Deletes == nil && len(req.Writes.TupleKeys) == 2
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+
+	err := service.MigrateRoleAssignments(ctx, rCtx, &graph.Resource{Name: "new-account"}, false)
+
+	require.NoError(t, err)
+}
+
+func TestService_MigrateRoleAssignments_FollowsContinuationToken(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	ownerObject := "role:core_platform-mesh_io_account/cluster-123/test-account/owner"
+	client.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+		return req.TupleKey.Object == ownerObject && req.ContinuationToken == ""
+	})).Return(&openfgav1.ReadResponse{
+		Tuples:            []*openfgav1.Tuple{{Key: &openfgav1.TupleKey{User: "user:first@example.com", Relation: "assignee", Object: ownerObject}}},
+		ContinuationToken: "next",
+	}, nil).Once()
+	client.EXPECT().Read(mock.Anything, mock.MatchedBy(func(req *openfgav1.ReadRequest) bool {
+		return req.TupleKey.Object == ownerObject && req.ContinuationToken == "next"
+	})).Return(&openfgav1.ReadResponse{
+		Tuples: []*openfgav1.Tuple{{Key: &openfgav1.TupleKey{User: "user:second@example.com", Relation: "assignee", Object: ownerObject}}},
+	}, nil).Once()
+	expectRoleAssignees(client, "member")
+
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 3
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+
+	err := service.MigrateRoleAssignments(ctx, rCtx, &graph.Resource{Name: "new-account"}, false)
+
+	require.NoError(t, err)
+}
+
+func TestService_MigrateRoleAssignments_ChunksWrites(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	users := make([]string, defaultMaxTuplesPerWrite+10)
+	for i := range users {
+		users[i] = "user:user" + string(rune('a'+i%26)) + string(rune('a'+i/26)) + "@example.com"
+	}
+	expectRoleAssignees(client, "owner", users...)
+	expectRoleAssignees(client, "member")
+
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == defaultMaxTuplesPerWrite
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+	// Remaining assignees plus the binding of the owner role
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 11
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+
+	err := service.MigrateRoleAssignments(ctx, rCtx, &graph.Resource{Name: "new-account"}, false)
+
+	require.NoError(t, err)
+}
+
+func TestService_MigrateRoleAssignments_ConfiguredChunkSize(t *testing.T) {
+	service, client := createTestService(t)
+	service.maxTuplesPerWrite = 2
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:a@example.com", "user:b@example.com", "user:c@example.com")
+	expectRoleAssignees(client, "member")
+
+	var writes, deletes []int
+	client.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, req *openfgav1.WriteRequest, _ ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+		if req.Writes != nil {
+			writes = append(writes, len(req.Writes.TupleKeys))
+		}
+		if req.Deletes != nil {
+			deletes = append(deletes, len(req.Deletes.TupleKeys))
+		}
+		return &openfgav1.WriteResponse{}, nil
+	})
+
+	err := service.MigrateRoleAssignments(ctx, rCtx, &graph.Resource{Name: "new-account"}, true)
+
+	require.NoError(t, err)
+	// Three assignees plus the binding of the owner role, on both the target and the source
+	assert.Equal(t, []int{2, 2}, writes)
+	assert.Equal(t, []int{2, 2}, deletes)
+}
+
+func TestService_MigrateRoleAssignments_SkipsDuplicates(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:owner@example.com")
+	expectRoleAssignees(client, "member")
+
+	duplicate := status.Error(codes.Code(openfgav1.ErrorCode_write_failed_due_to_invalid_input), "tuple already exists")
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 2
+	})).Return(nil, duplicate).Once()
+	// Falls back to writing the tuples one by one
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 1 && req.Writes.TupleKeys[0].Relation == "assignee"
+	})).Return(nil, duplicate).Once()
+	client.EXPECT().Write(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteRequest) bool {
+		return len(req.Writes.TupleKeys) == 1 && req.Writes.TupleKeys[0].Relation == "owner"
+	})).Return(&openfgav1.WriteResponse{}, nil).Once()
+
+	err := service.MigrateRoleAssignments(ctx, rCtx, &graph.Resource{Name: "new-account"}, false)
+
+	require.NoError(t, err)
+}
+
+func TestService_MigrateRoleAssignments_SameResource(t *testing.T) {
+	tests := []struct {
+		name   string
+		target *graph.Resource
+	}{
+		{name: "same name", target: &graph.Resource{Name: "test-account"}},
+		{name: "same name in another namespace", target: &graph.Resource{Name: "test-account", Namespace: ptr.To("other")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Nothing is read or written
+			service, _ := createTestService(t)
+			ctx, rCtx := createPreviewTestContext()
+
+			err := service.MigrateRoleAssignments(ctx, rCtx, tt.target, true)
+
+			assert.ErrorContains(t, err, "cannot migrate role assignments of resource test-account to itself")
+		})
+	}
+}
+
+func TestService_MigrateRoleAssignments_ReadError(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	client.EXPECT().Read(mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+
+	err := service.MigrateRoleAssignments(ctx, rCtx, &graph.Resource{Name: "new-account"}, true)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read assignees of role")
+}
+
+func TestService_MigrateRoleAssignments_WriteErrorKeepsSource(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:owner@example.com")
+	expectRoleAssignees(client, "member")
+
+	// No delete request may follow a failed write
+	client.EXPECT().Write(mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+
+	err := service.MigrateRoleAssignments(ctx, rCtx, &graph.Resource{Name: "new-account"}, true)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to write role assignments for resource new-account")
+}
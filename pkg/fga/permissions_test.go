@@ -0,0 +1,204 @@
+package fga
+
+import (
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	fgamocks "github.com/platform-mesh/iam-service/pkg/fga/mocks"
+)
+
+// expectPermissionModel returns a model in which owners are members, members may get the
+// account and owners may additionally update it and manage its roles
+func expectPermissionModel(client *fgamocks.OpenFGAServiceClient) {
+	direct := &openfgav1.Userset{Userset: &openfgav1.Userset_This{This: &openfgav1.DirectUserset{}}}
+	computed := func(relation string) *openfgav1.Userset {
+		return &openfgav1.Userset{Userset: &openfgav1.Userset_ComputedUserset{ComputedUserset: &openfgav1.ObjectRelation{Relation: relation}}}
+	}
+	union := func(children ...*openfgav1.Userset) *openfgav1.Userset {
+		return &openfgav1.Userset{Userset: &openfgav1.Userset_Union{Union: &openfgav1.Usersets{Child: children}}}
+	}
+	client.EXPECT().ReadAuthorizationModel(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelResponse{
+		AuthorizationModel: &openfgav1.AuthorizationModel{
+			Id: "model-123",
+			TypeDefinitions: []*openfgav1.TypeDefinition{{
+				Type: "core_platform-mesh_io_account",
+				Relations: map[string]*openfgav1.Userset{
+					"parent":           direct,
+					"owner":            direct,
+					"member":           union(direct, computed("owner")),
+					"get":              computed("member"),
+					"update":           computed("owner"),
+					"manage_iam_roles": computed("owner"),
+					"delete":           direct,
+				},
+			}},
+		},
+	}, nil).Once()
+}
+
+func TestService_UserPermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		owners   []string
+		members  []string
+		expected []string
+	}{
+		{
+			name:     "member",
+			members:  []string{"user:jane@example.com"},
+			expected: []string{"get"},
+		},
+		{
+			name:     "owner and member",
+			owners:   []string{"user:jane@example.com"},
+			members:  []string{"user:jane@example.com"},
+			expected: []string{"get", "manage_iam_roles", "update"},
+		},
+		{
+			name:     "owner in another casing",
+			owners:   []string{"user:Jane@Example.com"},
+			members:  []string{"user:jane@example.com"},
+			expected: []string{"get"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, client := createTestService(t)
+			ctx, rCtx := createPreviewTestContext()
+			expectPreviewStore(client)
+			expectPermissionModel(client)
+
+			expectRoleAssignees(client, "owner", tt.owners...)
+			expectRoleAssignees(client, "member", tt.members...)
+
+			permissions, err := service.UserPermissions(ctx, rCtx, "jane@example.com")
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, permissions)
+		})
+	}
+}
+
+func TestService_UserPermissions_NoRoles(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:other@example.com")
+	expectRoleAssignees(client, "member", "user:other@example.com")
+
+	permissions, err := service.UserPermissions(ctx, rCtx, "jane@example.com")
+
+	require.NoError(t, err)
+	assert.NotNil(t, permissions)
+	assert.Empty(t, permissions)
+}
+
+func TestService_UserHasRole(t *testing.T) {
+	tests := []struct {
+		name          string
+		checkResponse *openfgav1.CheckResponse
+		checkErr      error
+		expected      bool
+		expectedError string
+	}{
+		{
+			name:          "has role",
+			checkResponse: &openfgav1.CheckResponse{Allowed: true},
+			expected:      true,
+		},
+		{
+			name:          "lacks role",
+			checkResponse: &openfgav1.CheckResponse{Allowed: false},
+			expected:      false,
+		},
+		{
+			name:          "check error",
+			checkErr:      assert.AnError,
+			expectedError: "failed to check role owner of user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, client := createTestService(t)
+			ctx, rCtx := createPreviewTestContext()
+			expectPreviewStore(client)
+
+			client.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+				return req.StoreId == "store-123" &&
+					req.AuthorizationModelId == "model-123" &&
+					req.TupleKey.User == "user:jane@example.com" &&
+					req.TupleKey.Relation == "assignee" &&
+					req.TupleKey.Object == "role:core_platform-mesh_io_account/cluster-123/test-account/owner"
+			})).Return(tt.checkResponse, tt.checkErr).Once()
+
+			hasRole, err := service.UserHasRole(ctx, rCtx, "jane@example.com", "owner")
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				assert.False(t, hasRole)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, hasRole)
+		})
+	}
+}
+
+func TestService_UserHasRole_StaleModel(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	for _, modelID := range []string{"old-model", "new-model"} {
+		client.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+			AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: modelID}},
+		}, nil).Once()
+	}
+	client.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+		return req.AuthorizationModelId == "old-model"
+	})).Return(nil, status.Error(codes.Code(openfgav1.ErrorCode_authorization_model_not_found), "authorization model not found")).Once()
+	client.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+		return req.AuthorizationModelId == "new-model"
+	})).Return(&openfgav1.CheckResponse{Allowed: true}, nil).Once()
+
+	hasRole, err := service.UserHasRole(ctx, rCtx, "jane@example.com", "owner")
+
+	require.NoError(t, err)
+	assert.True(t, hasRole)
+}
+
+func TestService_UserHasRole_UnknownRole(t *testing.T) {
+	service, _ := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+
+	hasRole, err := service.UserHasRole(ctx, rCtx, "jane@example.com", "admin")
+
+	assert.ErrorContains(t, err, "role admin is not available")
+	assert.False(t, hasRole)
+}
+
+func TestService_UserHasRole_MatchesUserExactly(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	client.EXPECT().Check(mock.Anything, mock.MatchedBy(func(req *openfgav1.CheckRequest) bool {
+		return req.TupleKey.User == "user:Jane@Example.com"
+	})).Return(&openfgav1.CheckResponse{Allowed: false}, nil).Once()
+
+	hasRole, err := service.UserHasRole(ctx, rCtx, "Jane@Example.com", "owner")
+
+	require.NoError(t, err)
+	assert.False(t, hasRole)
+}
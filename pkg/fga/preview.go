@@ -0,0 +1,91 @@
+package fga
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/fga/util"
+	"go.opentelemetry.io/otel"
+
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
+	"github.com/platform-mesh/iam-service/pkg/fga/store"
+	"github.com/platform-mesh/iam-service/pkg/fga/tuples"
+	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/platform-mesh/iam-service/pkg/redact"
+	"github.com/platform-mesh/iam-service/pkg/roles"
+)
+
+// PreviewRoleAssignments computes the role changes AssignRolesToUsers would apply for the given
+// changes without writing anything to FGA. Invites are not covered as invited users hold no roles yet.
+func (s *Service) PreviewRoleAssignments(ctx context.Context, rctx graph.ResourceContext, changes []*graph.UserRoleChange) ([]*graph.RoleAssignmentPreview, error) {
+	ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "fga.PreviewRoleAssignments")
+	defer span.End()
+
+	clusterId, err := appcontext.GetClusterId(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster ID from context")
+	}
+
+	kctx, err := appcontext.GetKCPContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kcp user context")
+	}
+
+	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
+	}
+
+	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
+	if err != nil { // coverage-ignore
+		return nil, errors.Wrap(err, "failed to get role definitions for group resource %s/%s", rctx.Group, rctx.Kind)
+	}
+	availableRoles := roles.GetAvailableRoleIDs(roleDefinitions)
+
+	// Role objects of this resource share the prefix role:<fgaType>/<clusterId>/<resourceName>/
+	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+	rolePrefix := tuples.RoleObject(fgaTypeName, clusterId, rctx.Resource.Name, "")
+
+	previews := make([]*graph.RoleAssignmentPreview, 0, len(changes))
+	for _, change := range changes {
+		res, err := store.WithModelRetry(ctx, s.helper, s.client, kctx.OrganizationName, func(modelID string) (*openfgav1.ListObjectsResponse, error) {
+			return s.client.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+				StoreId:              storeID,
+				AuthorizationModelId: modelID,
+				Type:                 tuples.RoleType,
+				Relation:             tuples.AssigneeRelation,
+				User:                 tuples.User(change.UserID),
+			})
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list role objects for user %s", redact.Email(change.UserID))
+		}
+
+		var assigned []string
+		for _, object := range res.Objects {
+			if role, found := strings.CutPrefix(object, rolePrefix); found {
+				assigned = append(assigned, role)
+			}
+		}
+
+		preview := &graph.RoleAssignmentPreview{UserID: change.UserID}
+		for _, role := range change.Roles {
+			switch {
+			case !containsString(availableRoles, role):
+				preview.Rejected = append(preview.Rejected, role)
+			case slices.Contains(assigned, role):
+				if !slices.Contains(preview.Unchanged, role) {
+					preview.Unchanged = append(preview.Unchanged, role)
+				}
+			case !slices.Contains(preview.Added, role):
+				preview.Added = append(preview.Added, role)
+			}
+		}
+		previews = append(previews, preview)
+	}
+
+	return previews, nil
+}
@@ -0,0 +1,151 @@
+package fga
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
+	fgamocks "github.com/platform-mesh/iam-service/pkg/fga/mocks"
+	"github.com/platform-mesh/iam-service/pkg/graph"
+)
+
+func createPreviewTestContext() (context.Context, graph.ResourceContext) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant:        "test-tenant",
+		OrganizationName: "test-org",
+	})
+	ctx = appcontext.SetClusterId(ctx, "cluster-123")
+	log, _ := logger.New(logger.DefaultConfig())
+	ctx = logger.SetLoggerInContext(ctx, log)
+
+	rCtx := graph.ResourceContext{
+		Group: "core.platform-mesh.io",
+		Kind:  "Account",
+		Resource: &graph.Resource{
+			Name: "test-account",
+		},
+		AccountPath: "test-account",
+	}
+	return ctx, rCtx
+}
+
+func expectPreviewStore(client *fgamocks.OpenFGAServiceClient) {
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	client.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-123"}},
+	}, nil).Maybe()
+}
+
+func TestService_PreviewRoleAssignments(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	client.EXPECT().ListObjects(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListObjectsRequest) bool {
+		return req.StoreId == "store-123" &&
+			req.AuthorizationModelId == "model-123" &&
+			req.Type == "role" &&
+			req.Relation == "assignee" &&
+			req.User == "user:user1@example.com"
+	})).Return(&openfgav1.ListObjectsResponse{
+		Objects: []string{
+			"role:core_platform-mesh_io_account/cluster-123/test-account/owner",
+			// Roles on other resources don't count as assigned
+			"role:core_platform-mesh_io_account/cluster-123/other-account/member",
+			"role:core_platform-mesh_io_account/cluster-123/test-account-2/member",
+		},
+	}, nil)
+	client.EXPECT().ListObjects(mock.Anything, mock.MatchedBy(func(req *openfgav1.ListObjectsRequest) bool {
+		return req.User == "user:user2@example.com"
+	})).Return(&openfgav1.ListObjectsResponse{}, nil)
+
+	previews, err := service.PreviewRoleAssignments(ctx, rCtx, []*graph.UserRoleChange{
+		{UserID: "user1@example.com", Roles: []string{"owner", "member", "admin", "member"}},
+		{UserID: "user2@example.com", Roles: []string{"member"}},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []*graph.RoleAssignmentPreview{
+		{UserID: "user1@example.com", Added: []string{"member"}, Unchanged: []string{"owner"}, Rejected: []string{"admin"}},
+		{UserID: "user2@example.com", Added: []string{"member"}},
+	}, previews)
+}
+
+func TestService_PreviewRoleAssignments_MatchesAssignRolesToUsers(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	// user1 already holds owner on the account
+	client.EXPECT().ListObjects(mock.Anything, mock.Anything).Return(&openfgav1.ListObjectsResponse{
+		Objects: []string{"role:core_platform-mesh_io_account/cluster-123/test-account/owner"},
+	}, nil)
+
+	changes := []*graph.UserRoleChange{
+		{UserID: "user1@example.com", Roles: []string{"owner", "member", "admin"}},
+	}
+
+	previews, err := service.PreviewRoleAssignments(ctx, rCtx, changes)
+	require.NoError(t, err)
+	require.Len(t, previews, 1)
+
+	// Writes for existing tuples are rejected by FGA as duplicates, everything else gets written
+	var written []string
+	client.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, req *openfgav1.WriteRequest, _ ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+		tuple := req.Writes.TupleKeys[0]
+		if tuple.Relation != "assignee" {
+			return &openfgav1.WriteResponse{}, nil
+		}
+		if strings.HasSuffix(tuple.Object, "/owner") {
+			return nil, status.Error(codes.Code(openfgav1.ErrorCode_write_failed_due_to_invalid_input), "tuple already exists")
+		}
+		written = append(written, tuple.Object[strings.LastIndex(tuple.Object, "/")+1:])
+		return &openfgav1.WriteResponse{}, nil
+	})
+
+	result, err := service.AssignRolesToUsers(ctx, rCtx, changes, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, previews[0].Added, written)
+	assert.Len(t, result.Errors, len(previews[0].Rejected))
+}
+
+func TestService_PreviewRoleAssignments_ListObjectsError(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	client.EXPECT().ListObjects(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	previews, err := service.PreviewRoleAssignments(ctx, rCtx, []*graph.UserRoleChange{
+		{UserID: "user1@example.com", Roles: []string{"owner"}},
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, previews)
+	assert.Contains(t, err.Error(), "failed to list role objects for user use***@example.com")
+}
+
+func TestService_PreviewRoleAssignments_NoClusterID(t *testing.T) {
+	service, _ := createTestService(t)
+	_, rCtx := createPreviewTestContext()
+
+	previews, err := service.PreviewRoleAssignments(context.Background(), rCtx, nil)
+
+	assert.Error(t, err)
+	assert.Nil(t, previews)
+	assert.Contains(t, err.Error(), "cluster ID")
+}
@@ -0,0 +1,102 @@
+package fga
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"go.opentelemetry.io/otel"
+
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
+	"github.com/platform-mesh/iam-service/pkg/fga/tuples"
+	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/platform-mesh/iam-service/pkg/redact"
+	"github.com/platform-mesh/iam-service/pkg/roles"
+)
+
+// ReconcileReport lists the users holding roles on a resource that no longer exist in the IDM.
+type ReconcileReport struct {
+	// Checked is the number of users with roles on the resource that were looked up
+	Checked int
+	// Missing lists the users that don't exist in the IDM anymore, sorted
+	Missing []string
+	// Unchecked lists the users whose lookup failed, sorted. They are neither reported missing nor removed
+	Unchecked []string
+	// Removals reports the outcome of removing the missing users from the resource, if requested
+	Removals []*UserRemoval
+}
+
+// ReconcileUsers looks up every user holding a role on the resource in rctx in the IDM system and reports
+// the users that were deleted there, e.g. after their Keycloak account was removed. Lookups are best effort,
+// users that can't be looked up are reported as unchecked. If removeMissing is set, the missing users are
+// removed from the resource via RemoveUsers, which keeps the last owner in place.
+func (s *Service) ReconcileUsers(ctx context.Context, rctx graph.ResourceContext, removeMissing bool) (*ReconcileReport, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	log = log.MustChildLoggerWithAttributes("group", rctx.Group, "kind", rctx.Kind, "resource", rctx.Resource.Name)
+	ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "fga.ReconcileUsers")
+	defer span.End()
+
+	if s.idmChecker == nil {
+		return nil, errors.New("no IDM user checker configured")
+	}
+
+	clusterId, err := appcontext.GetClusterId(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster ID from context")
+	}
+
+	kctx, err := appcontext.GetKCPContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kcp user context")
+	}
+
+	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
+	}
+
+	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
+	if err != nil { // coverage-ignore
+		return nil, errors.Wrap(err, "failed to get role definitions for group resource %s/%s", rctx.Group, rctx.Kind)
+	}
+
+	assigned, err := s.readAssignedRoles(ctx, storeID, rctx, clusterId, roles.GetAvailableRoleIDs(roleDefinitions))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconcileReport{}
+	for user := range assigned {
+		userID, found := strings.CutPrefix(user, tuples.UserType+":")
+		if !found {
+			continue
+		}
+		report.Checked++
+
+		usr, err := s.idmChecker.UserByMail(ctx, userID)
+		if err != nil {
+			log.Warn().Err(err).Str("userId", redact.Email(userID)).Msg("Failed to look up user in IDM system")
+			report.Unchecked = append(report.Unchecked, userID)
+			continue
+		}
+		if usr == nil {
+			report.Missing = append(report.Missing, userID)
+		}
+	}
+	slices.Sort(report.Missing)
+	slices.Sort(report.Unchecked)
+	log.Info().Int("checked", report.Checked).Int("missing", len(report.Missing)).Int("unchecked", len(report.Unchecked)).Msg("Reconciled users with IDM system")
+
+	if !removeMissing || len(report.Missing) == 0 {
+		return report, nil
+	}
+
+	report.Removals, err = s.RemoveUsers(ctx, rctx, report.Missing)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to remove missing users from resource %s", rctx.Resource.Name)
+	}
+
+	return report, nil
+}
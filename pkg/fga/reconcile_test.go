@@ -0,0 +1,81 @@
+package fga
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	fgamocks "github.com/platform-mesh/iam-service/pkg/fga/mocks"
+	"github.com/platform-mesh/iam-service/pkg/graph"
+)
+
+func TestService_ReconcileUsers(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	mockIDMChecker := fgamocks.NewIDMUserChecker(t)
+	service.idmChecker = mockIDMChecker
+
+	expectRoleAssignees(client, "owner", "user:owner@example.com")
+	expectRoleAssignees(client, "member", "user:owner@example.com", "user:deleted@example.com", "user:gone@example.com", "user:flaky@example.com")
+
+	mockIDMChecker.EXPECT().UserByMail(mock.Anything, "owner@example.com").Return(&graph.User{Email: "owner@example.com"}, nil).Once()
+	mockIDMChecker.EXPECT().UserByMail(mock.Anything, "deleted@example.com").Return(nil, nil).Once()
+	mockIDMChecker.EXPECT().UserByMail(mock.Anything, "gone@example.com").Return(nil, nil).Once()
+	mockIDMChecker.EXPECT().UserByMail(mock.Anything, "flaky@example.com").Return(nil, assert.AnError).Once()
+
+	report, err := service.ReconcileUsers(ctx, rCtx, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Checked)
+	assert.Equal(t, []string{"deleted@example.com", "gone@example.com"}, report.Missing)
+	// Failed lookups are not mistaken for deleted users
+	assert.Equal(t, []string{"flaky@example.com"}, report.Unchecked)
+	assert.Nil(t, report.Removals)
+}
+
+func TestService_ReconcileUsers_RemoveMissing(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	mockIDMChecker := fgamocks.NewIDMUserChecker(t)
+	service.idmChecker = mockIDMChecker
+
+	// The assignments are read once for the reconciliation and once for the removal
+	for range 2 {
+		expectRoleAssignees(client, "owner", "user:deleted-owner@example.com")
+		expectRoleAssignees(client, "member", "user:deleted@example.com")
+	}
+
+	mockIDMChecker.EXPECT().UserByMail(mock.Anything, mock.Anything).Return(nil, nil).Times(2)
+
+	var deleted []string
+	client.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(recordDeletes(&deleted, ""))
+
+	report, err := service.ReconcileUsers(ctx, rCtx, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deleted-owner@example.com", "deleted@example.com"}, report.Missing)
+	require.Len(t, report.Removals, 2)
+
+	// The last owner is kept even if the account was deleted
+	assert.ErrorContains(t, report.Removals[0].Err, "the last owner of resource test-account")
+	assert.Equal(t, []string{"member"}, report.Removals[1].RemovedRoles)
+	assert.Equal(t, []string{
+		"user:deleted@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/member",
+	}, deleted)
+}
+
+func TestService_ReconcileUsers_NoIDMChecker(t *testing.T) {
+	service, _ := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+
+	report, err := service.ReconcileUsers(ctx, rCtx, false)
+
+	assert.Error(t, err)
+	assert.Nil(t, report)
+}
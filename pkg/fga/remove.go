@@ -0,0 +1,137 @@
+package fga
+
+import (
+	"context"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/fga/util"
+	"github.com/platform-mesh/golang-commons/logger"
+	"go.opentelemetry.io/otel"
+
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
+	"github.com/platform-mesh/iam-service/pkg/fga/tuples"
+	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/platform-mesh/iam-service/pkg/redact"
+	"github.com/platform-mesh/iam-service/pkg/roles"
+)
+
+// UserRemoval reports the outcome of removing a single user from a resource.
+type UserRemoval struct {
+	UserID string
+	// RemovedRoles lists the roles the user held on the resource and no longer does, ordered by rank
+	RemovedRoles []string
+	Err          error
+}
+
+// RemoveUsers removes every role the given users hold on the resource in rctx, e.g. when offboarding them
+// from a project. A failure on one user doesn't stop the others; the outcome of each is reported in the
+// order the users were passed. The resource never loses its last owner: a user whose removal would leave
+// nobody assigned to the owner role keeps all roles and is reported with an error instead.
+func (s *Service) RemoveUsers(ctx context.Context, rctx graph.ResourceContext, userIDs []string) ([]*UserRemoval, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+	log = log.MustChildLoggerWithAttributes("group", rctx.Group, "kind", rctx.Kind, "resource", rctx.Resource.Name)
+	ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "fga.RemoveUsers")
+	defer span.End()
+
+	clusterId, err := appcontext.GetClusterId(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster ID from context")
+	}
+
+	kctx, err := appcontext.GetKCPContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kcp user context")
+	}
+
+	storeID, err := s.helper.GetStoreID(ctx, s.client, kctx.OrganizationName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get store ID for organization %s", kctx.OrganizationName)
+	}
+
+	roleDefinitions, err := s.rolesRetriever.GetRoleDefinitions(rctx)
+	if err != nil { // coverage-ignore
+		return nil, errors.Wrap(err, "failed to get role definitions for group resource %s/%s", rctx.Group, rctx.Kind)
+	}
+
+	ownerRole, err := s.OwnerRole(rctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+	assigned, err := s.readAssignedRoles(ctx, storeID, rctx, clusterId, roles.GetAvailableRoleIDs(roleDefinitions))
+	if err != nil {
+		return nil, err
+	}
+
+	// The owners are counted up front for the last-owner guard
+	var owners int
+	for _, held := range assigned {
+		if containsString(held, ownerRole) {
+			owners++
+		}
+	}
+
+	removals := make([]*UserRemoval, 0, len(userIDs))
+	for _, userID := range userIDs {
+		removal := &UserRemoval{UserID: userID}
+		removals = append(removals, removal)
+
+		held := assigned[tuples.User(userID)]
+		if len(held) == 0 {
+			continue
+		}
+
+		isOwner := containsString(held, ownerRole)
+		if isOwner && owners <= 1 {
+			log.Warn().Str("userId", redact.Email(userID)).Msg("Refusing to remove the last owner")
+			removal.Err = errors.New("cannot remove user %s, the last owner of resource %s", redact.Email(userID), rctx.Resource.Name)
+			continue
+		}
+
+		deletes := make([]*openfgav1.TupleKeyWithoutCondition, 0, len(held))
+		for _, role := range held {
+			deletes = append(deletes, &openfgav1.TupleKeyWithoutCondition{
+				User:     tuples.User(userID),
+				Relation: tuples.AssigneeRelation,
+				Object:   tuples.RoleObject(fgaTypeName, clusterId, rctx.Resource.Name, role),
+			})
+		}
+		if err := s.deleteTuples(ctx, storeID, deletes); err != nil {
+			log.Error().Err(err).Str("userId", redact.Email(userID)).Msg("Failed to remove user from resource")
+			removal.Err = errors.Wrap(err, "failed to remove user %s from resource %s", redact.Email(userID), rctx.Resource.Name)
+			continue
+		}
+
+		removal.RemovedRoles = held
+		delete(assigned, tuples.User(userID))
+		if isOwner {
+			owners--
+		}
+	}
+	log.Info().Int("users", len(removals)).Msg("Removed users from resource")
+
+	return removals, nil
+}
+
+// readAssignedRoles reads the assignees of the given roles on the resource in rctx and returns the roles
+// each of them holds, keyed by the FGA user, e.g. user:<email>. Roles keep the order they were passed in.
+func (s *Service) readAssignedRoles(ctx context.Context, storeID string, rctx graph.ResourceContext, clusterId string, availableRoles []string) (map[string][]string, error) {
+	fgaTypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
+	assigned := map[string][]string{}
+	for _, role := range availableRoles {
+		assignees, err := s.readAll(ctx, storeID, &openfgav1.ReadRequestTupleKey{
+			Relation: tuples.AssigneeRelation,
+			Object:   tuples.RoleObject(fgaTypeName, clusterId, rctx.Resource.Name, role),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read assignees of role %s on resource %s", role, rctx.Resource.Name)
+		}
+
+		for _, assignee := range assignees {
+			assigned[assignee.Key.User] = append(assigned[assignee.Key.User], role)
+		}
+	}
+	return assigned, nil
+}
@@ -0,0 +1,136 @@
+package fga
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// recordDeletes records the deleted tuples and fails the deletion of tuples of the given user
+func recordDeletes(deleted *[]string, failingUser string) func(context.Context, *openfgav1.WriteRequest, ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+	return func(_ context.Context, req *openfgav1.WriteRequest, _ ...grpc.CallOption) (*openfgav1.WriteResponse, error) {
+		for _, key := range req.GetDeletes().GetTupleKeys() {
+			if key.User == failingUser {
+				return nil, assert.AnError
+			}
+		}
+		for _, key := range req.GetDeletes().GetTupleKeys() {
+			*deleted = append(*deleted, key.User+" "+key.Relation+" "+key.Object)
+		}
+		return &openfgav1.WriteResponse{}, nil
+	}
+}
+
+func TestService_RemoveUsers(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:owner1@example.com", "user:owner2@example.com")
+	expectRoleAssignees(client, "member", "user:owner1@example.com", "user:member@example.com")
+
+	var deleted []string
+	client.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(recordDeletes(&deleted, ""))
+
+	removals, err := service.RemoveUsers(ctx, rCtx, []string{"owner1@example.com", "member@example.com", "unknown@example.com"})
+
+	require.NoError(t, err)
+	require.Len(t, removals, 3)
+
+	assert.Equal(t, "owner1@example.com", removals[0].UserID)
+	assert.Equal(t, []string{"owner", "member"}, removals[0].RemovedRoles)
+	assert.NoError(t, removals[0].Err)
+
+	assert.Equal(t, "member@example.com", removals[1].UserID)
+	assert.Equal(t, []string{"member"}, removals[1].RemovedRoles)
+	assert.NoError(t, removals[1].Err)
+
+	// Users without roles on the resource have nothing to remove
+	assert.Equal(t, "unknown@example.com", removals[2].UserID)
+	assert.Empty(t, removals[2].RemovedRoles)
+	assert.NoError(t, removals[2].Err)
+
+	assert.Equal(t, []string{
+		"user:owner1@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/owner",
+		"user:owner1@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/member",
+		"user:member@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/member",
+	}, deleted)
+}
+
+func TestService_RemoveUsers_PartialFailure(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:owner1@example.com", "user:owner2@example.com")
+	expectRoleAssignees(client, "member", "user:member1@example.com", "user:member2@example.com")
+
+	var deleted []string
+	client.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(recordDeletes(&deleted, "user:member1@example.com"))
+
+	removals, err := service.RemoveUsers(ctx, rCtx, []string{"member1@example.com", "member2@example.com"})
+
+	require.NoError(t, err)
+	require.Len(t, removals, 2)
+
+	assert.Empty(t, removals[0].RemovedRoles)
+	assert.ErrorContains(t, removals[0].Err, "failed to remove user")
+
+	assert.Equal(t, []string{"member"}, removals[1].RemovedRoles)
+	assert.NoError(t, removals[1].Err)
+
+	assert.Equal(t, []string{
+		"user:member2@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/member",
+	}, deleted)
+}
+
+func TestService_RemoveUsers_LastOwner(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	expectRoleAssignees(client, "owner", "user:owner1@example.com", "user:owner2@example.com")
+	expectRoleAssignees(client, "member", "user:owner2@example.com", "user:member@example.com")
+
+	var deleted []string
+	client.EXPECT().Write(mock.Anything, mock.Anything).RunAndReturn(recordDeletes(&deleted, ""))
+
+	removals, err := service.RemoveUsers(ctx, rCtx, []string{"owner1@example.com", "owner2@example.com", "member@example.com"})
+
+	require.NoError(t, err)
+	require.Len(t, removals, 3)
+
+	assert.Equal(t, []string{"owner"}, removals[0].RemovedRoles)
+	assert.NoError(t, removals[0].Err)
+
+	// The remaining owner keeps all roles, the rest of the batch is still processed
+	assert.Empty(t, removals[1].RemovedRoles)
+	assert.ErrorContains(t, removals[1].Err, "the last owner of resource test-account")
+
+	assert.Equal(t, []string{"member"}, removals[2].RemovedRoles)
+	assert.NoError(t, removals[2].Err)
+
+	assert.Equal(t, []string{
+		"user:owner1@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/owner",
+		"user:member@example.com assignee role:core_platform-mesh_io_account/cluster-123/test-account/member",
+	}, deleted)
+}
+
+func TestService_RemoveUsers_ReadError(t *testing.T) {
+	service, client := createTestService(t)
+	ctx, rCtx := createPreviewTestContext()
+	expectPreviewStore(client)
+
+	client.EXPECT().Read(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	removals, err := service.RemoveUsers(ctx, rCtx, []string{"member@example.com"})
+
+	assert.Error(t, err)
+	assert.Nil(t, removals)
+	assert.Contains(t, err.Error(), "failed to read assignees of role owner on resource test-account")
+}
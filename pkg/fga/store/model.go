@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"os"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/platform-mesh/golang-commons/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// LoadAuthorizationModel reads an authorization model in OpenFGA's JSON format, e.g. the output of
+// `fga model transform`, and returns it as write request.
+// The store ID of the returned request is left empty. Unknown fields like a model "id" are ignored.
+func LoadAuthorizationModel(path string) (*openfgav1.WriteAuthorizationModelRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read authorization model file %s", path)
+	}
+
+	req := &openfgav1.WriteAuthorizationModelRequest{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, req); err != nil {
+		return nil, errors.Wrap(err, "failed to parse authorization model file %s", path)
+	}
+	// Everything else is validated by OpenFGA when the model is written
+	if len(req.TypeDefinitions) == 0 {
+		return nil, errors.New("authorization model file %s contains no type definitions", path)
+	}
+
+	return req, nil
+}
+
+// WriteLocalAuthorizationModel writes the authorization model from the file at path to the store of the
+// given organization and returns the ID of the new model. It is meant for local development only,
+// so that every dev environment evaluates requests against the same model.
+func WriteLocalAuthorizationModel(ctx context.Context, helper StoreHelper, conn openfgav1.OpenFGAServiceClient, orgID, path string) (string, error) {
+	req, err := LoadAuthorizationModel(path)
+	if err != nil {
+		return "", err
+	}
+
+	storeID, err := helper.GetStoreID(ctx, conn, orgID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get store ID for organization %s", orgID)
+	}
+	req.StoreId = storeID
+
+	res, err := conn.WriteAuthorizationModel(ctx, req)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to write authorization model to store %s", storeID)
+	}
+
+	return res.AuthorizationModelId, nil
+}
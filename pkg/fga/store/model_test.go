@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	fgamocks "github.com/platform-mesh/iam-service/pkg/fga/mocks"
+)
+
+func TestLoadAuthorizationModel(t *testing.T) {
+	req, err := LoadAuthorizationModel(filepath.Join("testdata", "model.json"))
+
+	require.NoError(t, err)
+	assert.Empty(t, req.StoreId)
+	assert.Equal(t, "1.1", req.SchemaVersion)
+	require.Len(t, req.TypeDefinitions, 3)
+	assert.Equal(t, "user", req.TypeDefinitions[0].Type)
+
+	account := req.TypeDefinitions[2]
+	assert.Equal(t, "core_platform-mesh_io_account", account.Type)
+	assert.NotNil(t, account.Relations["owner"].GetThis())
+	assert.Equal(t, "owner", account.Relations["get_iam_users"].GetComputedUserset().GetRelation())
+	assert.Equal(t, "assignee", account.Metadata.Relations["owner"].DirectlyRelatedUserTypes[0].GetRelation())
+}
+
+func TestLoadAuthorizationModel_Errors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte("model\n  schema 1.1"), 0o600))
+	empty := filepath.Join(dir, "empty.json")
+	require.NoError(t, os.WriteFile(empty, []byte(`{"schema_version": "1.1"}`), 0o600))
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedError string
+	}{
+		{name: "missing file", path: filepath.Join(dir, "missing.json"), expectedError: "failed to read authorization model file"},
+		{name: "not json", path: invalid, expectedError: "failed to parse authorization model file"},
+		{name: "no type definitions", path: empty, expectedError: "contains no type definitions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := LoadAuthorizationModel(tt.path)
+
+			assert.Error(t, err)
+			assert.Nil(t, req)
+			assert.Contains(t, err.Error(), tt.expectedError)
+		})
+	}
+}
+
+func TestWriteLocalAuthorizationModel(t *testing.T) {
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	helper := NewFGAStoreHelper(5 * time.Minute)
+	ctx := context.Background()
+
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	client.EXPECT().WriteAuthorizationModel(mock.Anything, mock.MatchedBy(func(req *openfgav1.WriteAuthorizationModelRequest) bool {
+		return req.StoreId == "store-123" &&
+			req.SchemaVersion == "1.1" &&
+			len(req.TypeDefinitions) == 3 &&
+			req.TypeDefinitions[1].Type == "role"
+	})).Return(&openfgav1.WriteAuthorizationModelResponse{AuthorizationModelId: "model-123"}, nil)
+
+	modelID, err := WriteLocalAuthorizationModel(ctx, helper, client, "test-org", filepath.Join("testdata", "model.json"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "model-123", modelID)
+}
+
+func TestWriteLocalAuthorizationModel_WriteError(t *testing.T) {
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	helper := NewFGAStoreHelper(5 * time.Minute)
+	ctx := context.Background()
+
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	client.EXPECT().WriteAuthorizationModel(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+	modelID, err := WriteLocalAuthorizationModel(ctx, helper, client, "test-org", filepath.Join("testdata", "model.json"))
+
+	assert.Error(t, err)
+	assert.Empty(t, modelID)
+	assert.Contains(t, err.Error(), "failed to write authorization model to store store-123")
+}
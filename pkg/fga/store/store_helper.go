@@ -15,6 +15,9 @@ import (
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/platform-mesh/golang-commons/errors"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/status"
+
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
 )
 
 // StoreHelper provides methods for managing OpenFGA store and model operations
@@ -32,7 +35,7 @@ type StoreHelper interface {
 	//
 	// Returns:
 	//   - string: The OpenFGA store ID
-	//   - error: Error if store is not found or API call fails
+	//   - error: Error if orgID is empty, store is not found or API call fails
 	GetStoreID(ctx context.Context, conn openfgav1.OpenFGAServiceClient, orgID string) (string, error)
 
 	// GetModelID retrieves the most recent authorization model ID for a given organization.
@@ -49,6 +52,13 @@ type StoreHelper interface {
 	//   - string: The most recent authorization model ID
 	//   - error: Error if store/model is not found or API call fails
 	GetModelID(ctx context.Context, conn openfgav1.OpenFGAServiceClient, orgID string) (string, error)
+
+	// InvalidateModelID removes the cached authorization model ID of the given organization,
+	// so that the next GetModelID call retrieves the most recent model from OpenFGA.
+	//
+	// Parameters:
+	//   - orgID: Organization identifier
+	InvalidateModelID(orgID string)
 }
 
 // PMStoreHelper is the concrete implementation of StoreHelper that provides
@@ -61,6 +71,14 @@ type PMStoreHelper struct {
 	cache *expirable.LRU[string, string]
 }
 
+// cacheSize is the capacity of the helper's cache. Every organization takes two
+// entries, one for its store ID and one for its authorization model ID.
+const cacheSize = 10
+
+// MaxWarmUpOrganizations is the number of organizations whose IDs fit into the
+// helper's cache at the same time, and thereby the most WarmUp resolves.
+const MaxWarmUpOrganizations = cacheSize / 2
+
 // NewFGAStoreHelper creates a new instance of PMStoreHelper with the specified
 // time-to-live (TTL) for cached entries. The cache has a maximum capacity of 10
 // entries and uses LRU eviction policy when the capacity is exceeded.
@@ -72,7 +90,7 @@ type PMStoreHelper struct {
 // Returns:
 //   - StoreHelper: A new PMStoreHelper instance implementing the StoreHelper interface
 func NewFGAStoreHelper(ttl time.Duration) StoreHelper {
-	return &PMStoreHelper{cache: expirable.NewLRU[string, string](10, nil, ttl)}
+	return &PMStoreHelper{cache: expirable.NewLRU[string, string](cacheSize, nil, ttl)}
 }
 
 // GetStoreID implements the StoreHelper interface method to retrieve an OpenFGA store ID
@@ -88,6 +106,11 @@ func NewFGAStoreHelper(ttl time.Duration) StoreHelper {
 // Cache key format: "store-{orgID}"
 // Example: For orgID "my-org", cache key would be "store-my-org"
 func (d PMStoreHelper) GetStoreID(ctx context.Context, conn openfgav1.OpenFGAServiceClient, orgID string) (string, error) {
+	// An empty organization would silently match a store without a name
+	if orgID == "" {
+		return "", errors.New("organization must not be empty")
+	}
+
 	cacheKey := "store-" + orgID
 	s, ok := d.cache.Get(cacheKey)
 	if ok && s != "" {
@@ -128,7 +151,8 @@ func (d PMStoreHelper) GetStoreID(ctx context.Context, conn openfgav1.OpenFGASer
 // Example: For orgID "my-org", cache key would be "model-my-org"
 //
 // Note: OpenFGA returns authorization models in descending order by creation time,
-// so the first model in the response is the most recent one.
+// so the first model in the response is the most recent one. Only the first page of
+// a paginated response is read.
 func (d PMStoreHelper) GetModelID(ctx context.Context, conn openfgav1.OpenFGAServiceClient, orgID string) (string, error) {
 	cacheKey := "model-" + orgID
 	s, ok := d.cache.Get(cacheKey)
@@ -149,8 +173,101 @@ func (d PMStoreHelper) GetModelID(ctx context.Context, conn openfgav1.OpenFGASer
 		return "", errors.New("no authorization models in response. Cannot determine proper AuthorizationModelId")
 	}
 
+	// Models are returned newest first, so the first page always holds the latest model and its
+	// continuation token, which only leads to older versions, is not followed
 	modelID := res.AuthorizationModels[0].Id
 	d.cache.Add(cacheKey, modelID)
 
 	return modelID, nil
 }
+
+// InvalidateModelID implements the StoreHelper interface method to drop the cached
+// authorization model ID of the specified organization. It is used when OpenFGA no
+// longer knows the cached model, e.g. after the model was re-published.
+//
+// Cache key format: "model-{orgID}"
+func (d PMStoreHelper) InvalidateModelID(orgID string) {
+	d.cache.Remove("model-" + orgID)
+}
+
+// WarmUp resolves the store and authorization model IDs of the given organizations so that
+// the first requests after startup are served from the helper's cache. Organizations that
+// can't be resolved are logged and skipped. It stops and returns the context's error as soon
+// as ctx is canceled.
+//
+// The cache holds up to 10 entries and every organization takes two of them, its store
+// and its model ID. Only the first MaxWarmUpOrganizations organizations are warmed up,
+// more would just evict the ones resolved before.
+func WarmUp(ctx context.Context, helper StoreHelper, conn openfgav1.OpenFGAServiceClient, orgIDs []string) error {
+	if len(orgIDs) > MaxWarmUpOrganizations {
+		log.Warn().Int("organizations", len(orgIDs)).Int("max", MaxWarmUpOrganizations).Msg("More organizations than fit into the store cache, warming up the first ones only")
+		orgIDs = orgIDs[:MaxWarmUpOrganizations]
+	}
+
+	for i, orgID := range orgIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if _, err := helper.GetModelID(ctx, conn, orgID); err != nil {
+			log.Warn().Err(err).Str("organization", orgID).Msg("Failed to warm up store cache")
+			continue
+		}
+		log.Debug().Str("organization", orgID).Int("warmed", i+1).Int("total", len(orgIDs)).Msg("Warmed up store cache")
+	}
+	return nil
+}
+
+// IsModelNotFoundError reports whether err is the gRPC error OpenFGA returns for
+// requests against an authorization model that does not exist (anymore).
+func IsModelNotFoundError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	s, ok := status.FromError(err)
+	return ok && int32(s.Code()) == int32(openfgav1.ErrorCode_authorization_model_not_found)
+}
+
+// ResolveModelID returns the authorization model ID that requests for the given
+// organization should be evaluated against. A model pinned in the request context
+// (see appcontext.SetAuthorizationModelId) takes precedence, otherwise the most
+// recent model is retrieved via the helper's GetModelID.
+//
+// Passing the resolved ID on Check and ListUsers requests keeps authorization
+// decisions stable while a new model is being rolled out.
+func ResolveModelID(ctx context.Context, helper StoreHelper, conn openfgav1.OpenFGAServiceClient, orgID string) (string, error) {
+	if modelID, ok := appcontext.GetAuthorizationModelId(ctx); ok {
+		return modelID, nil
+	}
+	return helper.GetModelID(ctx, conn, orgID)
+}
+
+// WithModelRetry calls fn with the authorization model ID resolved for the organization, see ResolveModelID.
+// If OpenFGA doesn't know the model (anymore), e.g. because it was re-published, the cached model ID is
+// invalidated and fn is retried once with the most recent model. Models pinned in the request context are
+// not replaced. Every request passing a model ID should be sent through it.
+func WithModelRetry[T any](ctx context.Context, helper StoreHelper, conn openfgav1.OpenFGAServiceClient, orgID string, fn func(modelID string) (T, error)) (T, error) {
+	var zero T
+	modelID, err := ResolveModelID(ctx, helper, conn, orgID)
+	if err != nil {
+		return zero, errors.Wrap(err, "failed to get authorization model ID for organization %s", orgID)
+	}
+
+	res, err := fn(modelID)
+	if !IsModelNotFoundError(err) {
+		return res, err
+	}
+	if _, pinned := appcontext.GetAuthorizationModelId(ctx); pinned {
+		return zero, errors.New("authorization model %s not found", modelID)
+	}
+
+	helper.InvalidateModelID(orgID)
+	latestModelID, err := helper.GetModelID(ctx, conn, orgID)
+	if err != nil {
+		return zero, errors.Wrap(err, "failed to get authorization model ID for organization %s", orgID)
+	}
+
+	log.Info().Str("staleModelId", modelID).Str("modelId", latestModelID).Str("organization", orgID).Msg("Retrying with latest authorization model")
+	return fn(latestModelID)
+}
@@ -3,12 +3,17 @@ package store
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
 	fgamocks "github.com/platform-mesh/iam-service/pkg/fga/mocks"
 )
 
@@ -102,6 +107,17 @@ func TestStoreHelper_GetStoreID_StoreNotFound(t *testing.T) {
 	assert.Contains(t, err.Error(), "store with name nonexistent-org not found")
 }
 
+func TestStoreHelper_GetStoreID_EmptyOrganization(t *testing.T) {
+	// No API call is expected for an empty organization
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	helper := NewFGAStoreHelper(5 * time.Minute)
+
+	storeID, err := helper.GetStoreID(context.Background(), client, "")
+
+	assert.EqualError(t, err, "organization must not be empty")
+	assert.Empty(t, storeID)
+}
+
 func TestStoreHelper_GetStoreID_ListStoresError(t *testing.T) {
 	client := fgamocks.NewOpenFGAServiceClient(t)
 	helper := NewFGAStoreHelper(5 * time.Minute)
@@ -159,6 +175,32 @@ func TestStoreHelper_GetModelID_Success(t *testing.T) {
 	assert.Equal(t, expectedModelID, modelID)
 }
 
+func TestStoreHelper_GetModelID_PaginatedModels(t *testing.T) {
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	helper := NewFGAStoreHelper(5 * time.Minute)
+
+	ctx := context.Background()
+	client.EXPECT().ListStores(ctx, &openfgav1.ListStoresRequest{}).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+
+	// Several model versions with more pages of older ones, only the first page is read
+	client.EXPECT().ReadAuthorizationModels(ctx, &openfgav1.ReadAuthorizationModelsRequest{StoreId: "store-123"}).
+		Return(&openfgav1.ReadAuthorizationModelsResponse{
+			AuthorizationModels: []*openfgav1.AuthorizationModel{
+				{Id: "01HZX5V3R8Q0M7C2T9D4K6N1PB"},
+				{Id: "01HZX5V3R8Q0M7C2T9D4K6N1PA"},
+				{Id: "01HVG2E6Y0F3W8B5J1S7A9QX4Z"},
+			},
+			ContinuationToken: "page-2",
+		}, nil).Once()
+
+	modelID, err := helper.GetModelID(ctx, client, "test-org")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "01HZX5V3R8Q0M7C2T9D4K6N1PB", modelID)
+}
+
 func TestStoreHelper_GetModelID_CachedResult(t *testing.T) {
 	client := fgamocks.NewOpenFGAServiceClient(t)
 	helper := NewFGAStoreHelper(5 * time.Minute)
@@ -202,6 +244,99 @@ func TestStoreHelper_GetModelID_CachedResult(t *testing.T) {
 	assert.Equal(t, expectedModelID, modelID2)
 }
 
+func TestWarmUp(t *testing.T) {
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	helper := NewFGAStoreHelper(5 * time.Minute)
+	ctx := context.Background()
+
+	client.EXPECT().ListStores(ctx, &openfgav1.ListStoresRequest{}).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-a", Name: "org-a"}, {Id: "store-b", Name: "org-b"}},
+	}, nil).Times(3)
+	client.EXPECT().ReadAuthorizationModels(ctx, &openfgav1.ReadAuthorizationModelsRequest{StoreId: "store-a"}).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-a"}},
+	}, nil).Once()
+	client.EXPECT().ReadAuthorizationModels(ctx, &openfgav1.ReadAuthorizationModelsRequest{StoreId: "store-b"}).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model-b"}},
+	}, nil).Once()
+
+	// Unknown organizations don't stop the others from being warmed up
+	err := WarmUp(ctx, helper, client, []string{"org-a", "unknown-org", "org-b"})
+	assert.NoError(t, err)
+
+	// Served from the cache without any further API calls
+	storeID, err := helper.GetStoreID(ctx, client, "org-a")
+	assert.NoError(t, err)
+	assert.Equal(t, "store-a", storeID)
+	modelID, err := helper.GetModelID(ctx, client, "org-b")
+	assert.NoError(t, err)
+	assert.Equal(t, "model-b", modelID)
+}
+
+func TestWarmUp_CapsOrganizations(t *testing.T) {
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	helper := NewFGAStoreHelper(5 * time.Minute)
+	ctx := context.Background()
+
+	orgIDs := make([]string, 0, MaxWarmUpOrganizations+1)
+	stores := make([]*openfgav1.Store, 0, MaxWarmUpOrganizations+1)
+	for i := 0; i <= MaxWarmUpOrganizations; i++ {
+		orgID := fmt.Sprintf("org-%d", i)
+		orgIDs = append(orgIDs, orgID)
+		stores = append(stores, &openfgav1.Store{Id: "store-" + orgID, Name: orgID})
+	}
+
+	// The organization beyond the cache size is never resolved
+	client.EXPECT().ListStores(ctx, &openfgav1.ListStoresRequest{}).Return(&openfgav1.ListStoresResponse{Stores: stores}, nil).Times(MaxWarmUpOrganizations)
+	client.EXPECT().ReadAuthorizationModels(ctx, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+		AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "model"}},
+	}, nil).Times(MaxWarmUpOrganizations)
+
+	err := WarmUp(ctx, helper, client, orgIDs)
+	assert.NoError(t, err)
+}
+
+func TestWarmUp_Canceled(t *testing.T) {
+	// No API calls are expected once the context is canceled
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	helper := NewFGAStoreHelper(5 * time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := WarmUp(ctx, helper, client, []string{"org-a"})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStoreHelper_InvalidateModelID(t *testing.T) {
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	helper := NewFGAStoreHelper(5 * time.Minute)
+
+	ctx := context.Background()
+
+	// The store ID stays cached, only the model is read again
+	client.EXPECT().ListStores(ctx, &openfgav1.ListStoresRequest{}).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil).Once()
+	client.EXPECT().ReadAuthorizationModels(ctx, &openfgav1.ReadAuthorizationModelsRequest{StoreId: "store-123"}).
+		Return(&openfgav1.ReadAuthorizationModelsResponse{
+			AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "old-model"}},
+		}, nil).Once()
+	client.EXPECT().ReadAuthorizationModels(ctx, &openfgav1.ReadAuthorizationModelsRequest{StoreId: "store-123"}).
+		Return(&openfgav1.ReadAuthorizationModelsResponse{
+			AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "new-model"}},
+		}, nil).Once()
+
+	modelID, err := helper.GetModelID(ctx, client, "test-org")
+	assert.NoError(t, err)
+	assert.Equal(t, "old-model", modelID)
+
+	helper.InvalidateModelID("test-org")
+
+	modelID, err = helper.GetModelID(ctx, client, "test-org")
+	assert.NoError(t, err)
+	assert.Equal(t, "new-model", modelID)
+}
+
 func TestStoreHelper_GetModelID_GetStoreIDError(t *testing.T) {
 	client := fgamocks.NewOpenFGAServiceClient(t)
 	helper := NewFGAStoreHelper(5 * time.Minute)
@@ -283,3 +418,119 @@ func TestStoreHelper_GetModelID_ReadModelsError(t *testing.T) {
 	assert.Empty(t, modelID)
 	assert.Contains(t, err.Error(), "read models failed")
 }
+
+func TestResolveModelID_Pinned(t *testing.T) {
+	ctx := appcontext.SetAuthorizationModelId(context.Background(), "pinned-model")
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	helper := NewFGAStoreHelper(time.Minute)
+
+	// No lookup happens for a pinned model
+	modelID, err := ResolveModelID(ctx, helper, client, "test-org")
+	assert.NoError(t, err)
+	assert.Equal(t, "pinned-model", modelID)
+}
+
+func TestResolveModelID_Latest(t *testing.T) {
+	ctx := context.Background()
+	client := fgamocks.NewOpenFGAServiceClient(t)
+	helper := NewFGAStoreHelper(time.Minute)
+
+	client.EXPECT().ListStores(ctx, &openfgav1.ListStoresRequest{}).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil)
+	client.EXPECT().ReadAuthorizationModels(ctx, &openfgav1.ReadAuthorizationModelsRequest{StoreId: "store-123"}).
+		Return(&openfgav1.ReadAuthorizationModelsResponse{
+			AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: "latest-model"}},
+		}, nil)
+
+	modelID, err := ResolveModelID(ctx, helper, client, "test-org")
+	assert.NoError(t, err)
+	assert.Equal(t, "latest-model", modelID)
+}
+
+func TestIsModelNotFoundError(t *testing.T) {
+	assert.False(t, IsModelNotFoundError(nil))
+	assert.False(t, IsModelNotFoundError(errors.New("connection refused")))
+	assert.False(t, IsModelNotFoundError(status.Error(codes.Code(openfgav1.ErrorCode_write_failed_due_to_invalid_input), "invalid")))
+	assert.True(t, IsModelNotFoundError(status.Error(codes.Code(openfgav1.ErrorCode_authorization_model_not_found), "model not found")))
+}
+
+// expectLatestModels expects the store lookup once and returns the given model as latest model, one per lookup
+func expectLatestModels(client *fgamocks.OpenFGAServiceClient, modelIDs ...string) {
+	client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(&openfgav1.ListStoresResponse{
+		Stores: []*openfgav1.Store{{Id: "store-123", Name: "test-org"}},
+	}, nil).Once()
+	for _, modelID := range modelIDs {
+		client.EXPECT().ReadAuthorizationModels(mock.Anything, mock.Anything).Return(&openfgav1.ReadAuthorizationModelsResponse{
+			AuthorizationModels: []*openfgav1.AuthorizationModel{{Id: modelID}},
+		}, nil).Once()
+	}
+}
+
+func TestWithModelRetry(t *testing.T) {
+	modelNotFound := status.Error(codes.Code(openfgav1.ErrorCode_authorization_model_not_found), "model not found")
+
+	t.Run("stale model is retried with the latest model", func(t *testing.T) {
+		client := fgamocks.NewOpenFGAServiceClient(t)
+		expectLatestModels(client, "old-model", "new-model")
+		helper := NewFGAStoreHelper(time.Minute)
+
+		var called []string
+		res, err := WithModelRetry(context.Background(), helper, client, "test-org", func(modelID string) (string, error) {
+			called = append(called, modelID)
+			if modelID == "old-model" {
+				return "", modelNotFound
+			}
+			return "ok", nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", res)
+		assert.Equal(t, []string{"old-model", "new-model"}, called)
+
+		// The latest model is cached for later calls
+		modelID, err := helper.GetModelID(context.Background(), client, "test-org")
+		assert.NoError(t, err)
+		assert.Equal(t, "new-model", modelID)
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		client := fgamocks.NewOpenFGAServiceClient(t)
+		expectLatestModels(client, "model-123")
+
+		calls := 0
+		_, err := WithModelRetry(context.Background(), NewFGAStoreHelper(time.Minute), client, "test-org", func(string) (string, error) {
+			calls++
+			return "", assert.AnError
+		})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("pinned model is not replaced", func(t *testing.T) {
+		client := fgamocks.NewOpenFGAServiceClient(t)
+		ctx := appcontext.SetAuthorizationModelId(context.Background(), "pinned-model")
+
+		calls := 0
+		_, err := WithModelRetry(ctx, NewFGAStoreHelper(time.Minute), client, "test-org", func(string) (string, error) {
+			calls++
+			return "", modelNotFound
+		})
+
+		assert.ErrorContains(t, err, "authorization model pinned-model not found")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("model lookup error", func(t *testing.T) {
+		client := fgamocks.NewOpenFGAServiceClient(t)
+		client.EXPECT().ListStores(mock.Anything, mock.Anything).Return(nil, assert.AnError)
+
+		_, err := WithModelRetry(context.Background(), NewFGAStoreHelper(time.Minute), client, "test-org", func(string) (string, error) {
+			t.Fatal("must not be called without a model")
+			return "", nil
+		})
+
+		assert.ErrorContains(t, err, "failed to get authorization model ID for organization test-org")
+	})
+}
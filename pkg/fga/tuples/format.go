@@ -0,0 +1,57 @@
+package tuples
+
+import "fmt"
+
+const (
+	// RoleType is the FGA type of role objects
+	RoleType = "role"
+	// UserType is the FGA type of users
+	UserType = "user"
+	// ServiceAccountType is the FGA type of service accounts, i.e. callers without a user mail
+	ServiceAccountType = "serviceaccount"
+	// AssigneeRelation is the relation between a user and a role object
+	AssigneeRelation = "assignee"
+)
+
+// RoleObjectID returns the ID of the role object for a role on a resource, e.g.
+// "core_platform-mesh_io_account/cluster-1/my-account/owner"
+func RoleObjectID(fgaTypeName, clusterId, resourceName, role string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", fgaTypeName, clusterId, resourceName, role)
+}
+
+// RoleObject returns the role object for a role on a resource, e.g.
+// "role:core_platform-mesh_io_account/cluster-1/my-account/owner"
+func RoleObject(fgaTypeName, clusterId, resourceName, role string) string {
+	return fmt.Sprintf("%s:%s", RoleType, RoleObjectID(fgaTypeName, clusterId, resourceName, role))
+}
+
+// RoleAssigneeUserset returns the userset of all assignees of a role on a resource, e.g.
+// "role:core_platform-mesh_io_account/cluster-1/my-account/owner#assignee"
+func RoleAssigneeUserset(fgaTypeName, clusterId, resourceName, role string) string {
+	return fmt.Sprintf("%s#%s", RoleObject(fgaTypeName, clusterId, resourceName, role), AssigneeRelation)
+}
+
+// ResourceObjectID returns the ID of the FGA object of a resource, e.g. "cluster-1/default/my-deployment"
+// The namespace is only part of the ID if it is set
+func ResourceObjectID(clusterId string, namespace *string, name string) string {
+	if namespace != nil {
+		return fmt.Sprintf("%s/%s/%s", clusterId, *namespace, name)
+	}
+	return fmt.Sprintf("%s/%s", clusterId, name)
+}
+
+// ResourceObject returns the FGA object of a resource, e.g. "apps_deployment:cluster-1/default/my-deployment"
+// The namespace is only part of the object if it is set
+func ResourceObject(fgaTypeName, clusterId string, namespace *string, name string) string {
+	return fmt.Sprintf("%s:%s", fgaTypeName, ResourceObjectID(clusterId, namespace, name))
+}
+
+// Subject returns the FGA user for an ID of the given subject type, e.g. "serviceaccount:ci-bot"
+func Subject(subjectType, id string) string {
+	return fmt.Sprintf("%s:%s", subjectType, id)
+}
+
+// User returns the FGA user for a user ID, e.g. "user:jane@example.com"
+func User(userID string) string {
+	return Subject(UserType, userID)
+}
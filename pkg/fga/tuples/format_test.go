@@ -0,0 +1,47 @@
+package tuples
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleObjectID(t *testing.T) {
+	assert.Equal(t, "core_platform-mesh_io_account/cluster-1/my-account/owner",
+		RoleObjectID("core_platform-mesh_io_account", "cluster-1", "my-account", "owner"))
+}
+
+func TestRoleObject(t *testing.T) {
+	assert.Equal(t, "role:core_platform-mesh_io_account/cluster-1/my-account/owner",
+		RoleObject("core_platform-mesh_io_account", "cluster-1", "my-account", "owner"))
+}
+
+func TestRoleAssigneeUserset(t *testing.T) {
+	assert.Equal(t, "role:core_platform-mesh_io_account/cluster-1/my-account/owner#assignee",
+		RoleAssigneeUserset("core_platform-mesh_io_account", "cluster-1", "my-account", "owner"))
+}
+
+func TestResourceObjectID(t *testing.T) {
+	namespace := "default"
+
+	assert.Equal(t, "cluster-1/my-account", ResourceObjectID("cluster-1", nil, "my-account"))
+	assert.Equal(t, "cluster-1/default/my-deployment", ResourceObjectID("cluster-1", &namespace, "my-deployment"))
+}
+
+func TestResourceObject(t *testing.T) {
+	namespace := "default"
+
+	assert.Equal(t, "core_platform-mesh_io_account:cluster-1/my-account",
+		ResourceObject("core_platform-mesh_io_account", "cluster-1", nil, "my-account"))
+	assert.Equal(t, "apps_deployment:cluster-1/default/my-deployment",
+		ResourceObject("apps_deployment", "cluster-1", &namespace, "my-deployment"))
+}
+
+func TestUser(t *testing.T) {
+	assert.Equal(t, "user:jane@example.com", User("jane@example.com"))
+}
+
+func TestSubject(t *testing.T) {
+	assert.Equal(t, "user:jane@example.com", Subject(UserType, "jane@example.com"))
+	assert.Equal(t, "serviceaccount:ci-bot", Subject(ServiceAccountType, "ci-bot"))
+}
@@ -33,12 +33,7 @@ func GenerateContextualTuples(rctx *graph.ResourceContext, ai *accountsv1alpha1.
 
 	if !managedTuple(rctx.Group, rctx.Kind) {
 		resFGATypeName := util.ConvertToTypeName(rctx.Group, rctx.Kind)
-		var resObject string
-		if rctx.Resource.Namespace != nil {
-			resObject = fmt.Sprintf("%s:%s/%s/%s", resFGATypeName, ai.Spec.Account.GeneratedClusterId, *rctx.Resource.Namespace, rctx.Resource.Name)
-		} else {
-			resObject = fmt.Sprintf("%s:%s/%s", resFGATypeName, ai.Spec.Account.GeneratedClusterId, rctx.Resource.Name)
-		}
+		resObject := ResourceObject(resFGATypeName, ai.Spec.Account.GeneratedClusterId, rctx.Resource.Namespace, rctx.Resource.Name)
 
 		resTuple := &openfgav1.TupleKey{
 			Object:   resObject,
@@ -34,6 +34,24 @@ type PageInput struct {
 type Query struct {
 }
 
+// Users holding roles on a resource that no longer exist in the identity provider
+type ReconcileReport struct {
+	//  number of users with roles on the resource that were looked up
+	Checked int `json:"checked"`
+	//  users that don't exist in the identity provider anymore
+	Missing []string `json:"missing"`
+	//  users whose lookup failed. They are neither reported missing nor removed
+	Unchecked []string `json:"unchecked"`
+	//  outcome of removing the missing users, if requested
+	Removals []*UserRemoval `json:"removals"`
+}
+
+// An assigned role the authorization model already grants through another assigned role
+type RedundantRole struct {
+	Role         string `json:"role"`
+	SupersededBy string `json:"supersededBy"`
+}
+
 // Input for removing a role from a user
 type RemoveRoleInput struct {
 	UserID string `json:"userId"`
@@ -63,6 +81,17 @@ type Role struct {
 	Description string `json:"description"`
 }
 
+// Role changes assignRolesToUsers would apply for a single user. Role assignments are additive, so a preview never contains removals.
+type RoleAssignmentPreview struct {
+	UserID string `json:"userId"`
+	//  requested roles the user does not hold yet
+	Added []string `json:"added"`
+	//  requested roles the user already holds
+	Unchanged []string `json:"unchanged"`
+	//  requested roles that are not available for the resource
+	Rejected []string `json:"rejected"`
+}
+
 // Result of role assignment operation
 type RoleAssignmentResult struct {
 	Success       bool     `json:"success"`
@@ -70,6 +99,31 @@ type RoleAssignmentResult struct {
 	AssignedCount int      `json:"assignedCount"`
 }
 
+// Is a raw OpenFGA tuple of a role assignment, either assigning a user to a role or binding a role to its resource
+type RoleAssignmentTuple struct {
+	User     string `json:"user"`
+	Relation string `json:"relation"`
+	Object   string `json:"object"`
+}
+
+// Is a raw OpenFGA tuple of a role assignment as returned by exportRoleAssignments
+type RoleAssignmentTupleInput struct {
+	User     string `json:"user"`
+	Relation string `json:"relation"`
+	Object   string `json:"object"`
+}
+
+// Inconsistencies in the role assignments of a single user
+type RoleAudit struct {
+	UserID string `json:"userId"`
+	//  roles explicitly assigned to the user, highest ranked role first
+	Roles []string `json:"roles"`
+	//  roles assigned more than once, e.g. to the same email in different casing
+	Duplicates []string `json:"duplicates"`
+	//  assigned roles already granted by another assigned role
+	Redundant []*RedundantRole `json:"redundant"`
+}
+
 // Result of role removal operation
 type RoleRemovalResult struct {
 	Success     bool    `json:"success"`
@@ -99,6 +153,14 @@ type UserConnection struct {
 	OwnersCount int `json:"ownersCount"`
 }
 
+// Outcome of removing a single user from a resource
+type UserRemoval struct {
+	UserID string `json:"userId"`
+	//  roles the user held on the resource and no longer does, highest ranked role first
+	RemovedRoles []string `json:"removedRoles"`
+	Error        *string  `json:"error,omitempty"`
+}
+
 // Holds information about a specific user and a list of roles that should be assigned to the user
 type UserRoleChange struct {
 	UserID string   `json:"userId"`
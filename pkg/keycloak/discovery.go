@@ -0,0 +1,60 @@
+package keycloak
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+
+	"github.com/platform-mesh/iam-service/pkg/config"
+)
+
+const (
+	// defaultDiscoveryRetryInterval is used when no positive retry interval is configured
+	defaultDiscoveryRetryInterval = time.Second
+	// maxDiscoveryRetryInterval caps the exponential backoff between discovery attempts
+	maxDiscoveryRetryInterval = 15 * time.Second
+)
+
+// newProvider discovers the OIDC provider of the issuer. Failed attempts are retried with exponential
+// backoff until the configured timeout is used up, so that a Keycloak that is still starting doesn't
+// abort the service startup. The error of the last attempt is returned once the timeout is exceeded.
+func newProvider(ctx context.Context, issuer string, cfg config.KeycloakDiscoveryConfig) (*oidc.Provider, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+
+	deadline := time.Now().Add(cfg.Timeout)
+	delay := cfg.RetryInterval
+	if delay <= 0 {
+		delay = defaultDiscoveryRetryInterval
+	}
+
+	for attempt := 1; ; attempt++ {
+		provider, err := oidc.NewProvider(ctx, issuer)
+		if err == nil {
+			return provider, nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, errors.Wrap(err, "OIDC provider discovery failed after %d attempts", attempt)
+		}
+
+		delay = min(delay, remaining)
+		log.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Dur("retry_in", delay).
+			Msg("OIDC provider discovery failed, retrying")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Wrap(ctx.Err(), "context done while waiting to retry OIDC provider discovery")
+		case <-timer.C:
+		}
+		delay = min(2*delay, maxDiscoveryRetryInterval)
+	}
+}
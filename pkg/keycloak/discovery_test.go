@@ -0,0 +1,102 @@
+package keycloak
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/platform-mesh/iam-service/pkg/config"
+)
+
+// newDiscoveryServer serves the OIDC discovery document of the master realm once the given number
+// of requests has been answered with 503, and counts the discovery requests
+func newDiscoveryServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/realms/master/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		if requests.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		issuer := server.URL + "/realms/master"
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": issuer + "/protocol/openid-connect/auth",
+			"token_endpoint":         issuer + "/protocol/openid-connect/token",
+			"jwks_uri":               issuer + "/protocol/openid-connect/certs",
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestNew_DiscoveryRetriedUntilKeycloakIsUp(t *testing.T) {
+	server, requests := newDiscoveryServer(t, 2)
+
+	cfg := createKeycloakTestConfig(server.URL, "test-client", "test-client-secret", false, 0)
+	cfg.Keycloak.Discovery = config.KeycloakDiscoveryConfig{Timeout: 5 * time.Second, RetryInterval: 10 * time.Millisecond}
+
+	service, err := New(context.Background(), cfg)
+
+	require.NoError(t, err)
+	assert.NotNil(t, service)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestNew_DiscoveryTimeoutExceeded(t *testing.T) {
+	server, requests := newDiscoveryServer(t, 1000)
+
+	cfg := createKeycloakTestConfig(server.URL, "test-client", "test-client-secret", false, 0)
+	cfg.Keycloak.Discovery = config.KeycloakDiscoveryConfig{Timeout: 100 * time.Millisecond, RetryInterval: 10 * time.Millisecond}
+
+	start := time.Now()
+	service, err := New(context.Background(), cfg)
+
+	assert.Error(t, err)
+	assert.Nil(t, service)
+	assert.Contains(t, err.Error(), "failed to create OIDC provider")
+	assert.Greater(t, requests.Load(), int32(1))
+	// The timeout bounds the total wait, apart from the duration of the last attempt
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestNew_DiscoveryWithoutRetries(t *testing.T) {
+	server, requests := newDiscoveryServer(t, 1)
+
+	cfg := createKeycloakTestConfig(server.URL, "test-client", "test-client-secret", false, 0)
+
+	service, err := New(context.Background(), cfg)
+
+	assert.Error(t, err)
+	assert.Nil(t, service)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestNewProvider_ContextCanceled(t *testing.T) {
+	server, _ := newDiscoveryServer(t, 1000)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	provider, err := newProvider(ctx, server.URL+"/realms/master", config.KeycloakDiscoveryConfig{Timeout: time.Minute, RetryInterval: time.Second})
+
+	assert.Error(t, err)
+	assert.Nil(t, provider)
+	assert.Contains(t, err.Error(), "context done while waiting to retry OIDC provider discovery")
+}
@@ -19,8 +19,19 @@ type KeycloakService interface {
 	// Updates the UserRoles slice in-place with FirstName, LastName, and UserID from Keycloak
 	EnrichUserRoles(ctx context.Context, userRoles []*graph.UserRoles) error
 
+	// RefreshUserRoles drops the cached profiles of the given users and enriches them again
+	// Returns the number of users found in Keycloak
+	RefreshUserRoles(ctx context.Context, userRoles []*graph.UserRoles) (int, error)
+
 	// GetUsers retrieves all users from Keycloak
 	GetUsers(ctx context.Context) ([]*graph.User, error)
+
+	// StreamAllUsers calls fn for every page of users from Keycloak, an error from fn stops the iteration.
+	// Returns ErrTruncated if not all users could be listed.
+	StreamAllUsers(ctx context.Context, fn func(page []*graph.User) error) error
+
+	// SearchUsers retrieves up to limit users whose username, first name, last name or email starts with the query
+	SearchUsers(ctx context.Context, query string, limit int) ([]*graph.User, error)
 }
 
 // Ensure Service implements KeycloakService interface
@@ -0,0 +1,83 @@
+package keycloak
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/platform-mesh/golang-commons/errors"
+	"github.com/platform-mesh/golang-commons/logger"
+	"golang.org/x/time/rate"
+
+	"github.com/platform-mesh/iam-service/pkg/config"
+	keycloakClient "github.com/platform-mesh/iam-service/pkg/keycloak/client"
+)
+
+const (
+	// maxRateLimitRetries is the number of times a request rejected with 429 is retried
+	maxRateLimitRetries = 3
+	// defaultRetryAfter is used when Keycloak answers 429 without a usable Retry-After header
+	defaultRetryAfter = time.Second
+)
+
+// newRateLimiter creates a token-bucket limiter from the config
+// Returns nil if rate limiting is disabled (RPS <= 0)
+func newRateLimiter(cfg config.KeycloakRateLimitConfig) *rate.Limiter {
+	if cfg.RPS <= 0 {
+		return nil
+	}
+
+	burst := cfg.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(cfg.RPS), burst)
+}
+
+// getUsers calls GetUsersWithResponse respecting the configured rate limit.
+// Responses with status 429 are retried after the delay announced by Keycloak's Retry-After header.
+func (s *Service) getUsers(ctx context.Context, realm string, params *keycloakClient.GetUsersParams) (*keycloakClient.GetUsersResponse, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+
+	for attempt := 0; ; attempt++ {
+		if s.limiter != nil {
+			if err := s.limiter.Wait(ctx); err != nil {
+				return nil, errors.Wrap(err, "failed to wait for keycloak rate limiter")
+			}
+		}
+
+		resp, err := s.keycloakClient.GetUsersWithResponse(ctx, realm, params)
+		if err != nil || resp.StatusCode() != http.StatusTooManyRequests || attempt >= maxRateLimitRetries {
+			return resp, err
+		}
+
+		delay := retryAfter(resp.HTTPResponse)
+		log.Warn().
+			Int("attempt", attempt+1).
+			Dur("retry_after", delay).
+			Msg("Keycloak rate limit reached, backing off")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Wrap(ctx.Err(), "context done while waiting to retry keycloak request")
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter extracts the backoff delay from the Retry-After header of a 429 response.
+// Only the delay-seconds form is supported, anything else falls back to defaultRetryAfter.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return defaultRetryAfter
+	}
+
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return defaultRetryAfter
+	}
+	return time.Duration(seconds) * time.Second
+}
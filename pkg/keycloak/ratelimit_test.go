@@ -0,0 +1,166 @@
+package keycloak
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"k8s.io/utils/ptr"
+
+	"github.com/platform-mesh/iam-service/pkg/config"
+	appcontext "github.com/platform-mesh/iam-service/pkg/context"
+	keycloakClient "github.com/platform-mesh/iam-service/pkg/keycloak/client"
+	"github.com/platform-mesh/iam-service/pkg/keycloak/mocks"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	assert.Nil(t, newRateLimiter(config.KeycloakRateLimitConfig{}))
+	assert.Nil(t, newRateLimiter(config.KeycloakRateLimitConfig{RPS: -1, Burst: 5}))
+
+	limiter := newRateLimiter(config.KeycloakRateLimitConfig{RPS: 10, Burst: 5})
+	require.NotNil(t, limiter)
+	assert.Equal(t, rate.Limit(10), limiter.Limit())
+	assert.Equal(t, 5, limiter.Burst())
+
+	// Burst below 1 would block every request
+	limiter = newRateLimiter(config.KeycloakRateLimitConfig{RPS: 10})
+	require.NotNil(t, limiter)
+	assert.Equal(t, 1, limiter.Burst())
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     *http.Response
+		expected time.Duration
+	}{
+		{name: "nil response", resp: nil, expected: defaultRetryAfter},
+		{name: "missing header", resp: &http.Response{Header: http.Header{}}, expected: defaultRetryAfter},
+		{name: "seconds", resp: &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}, expected: 3 * time.Second},
+		{name: "http date is not supported", resp: &http.Response{Header: http.Header{"Retry-After": []string{"Wed, 21 Oct 2015 07:28:00 GMT"}}}, expected: defaultRetryAfter},
+		{name: "negative", resp: &http.Response{Header: http.Header{"Retry-After": []string{"-1"}}}, expected: defaultRetryAfter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, retryAfter(tt.resp))
+		})
+	}
+}
+
+func TestGetUsers_RateLimiterThrottles(t *testing.T) {
+	ctx := context.Background()
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	service := &Service{
+		keycloakClient: mockClient,
+		// One token up front, then one every 100ms
+		limiter: rate.NewLimiter(rate.Limit(10), 1),
+	}
+
+	mockClient.EXPECT().GetUsersWithResponse(mock.Anything, "test-realm", mock.Anything, mock.Anything).
+		Return(&keycloakClient.GetUsersResponse{
+			HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+			JSON200:      &[]keycloakClient.UserRepresentation{},
+		}, nil).Times(3)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := service.getUsers(ctx, "test-realm", &keycloakClient.GetUsersParams{})
+		require.NoError(t, err)
+	}
+
+	// The second and third request each had to wait for a new token
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestUserByMail_RetriesAfterTooManyRequests(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	service := &Service{
+		keycloakClient: mockClient,
+	}
+
+	userEmail := "test@example.com"
+	tooManyRequests := &keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": []string{"1"}},
+		},
+	}
+	success := &keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200: &[]keycloakClient.UserRepresentation{
+			{Id: ptr.To("test-user-id"), Email: &userEmail},
+		},
+	}
+
+	mockClient.EXPECT().GetUsersWithResponse(mock.Anything, "test-realm", mock.Anything, mock.Anything).
+		Return(tooManyRequests, nil).Once()
+	mockClient.EXPECT().GetUsersWithResponse(mock.Anything, "test-realm", mock.Anything, mock.Anything).
+		Return(success, nil).Once()
+
+	start := time.Now()
+	result, err := service.UserByMail(ctx, userEmail)
+
+	assert.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, "test-user-id", result.UserID)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestGetUsers_TooManyRequestsRetriesExhausted(t *testing.T) {
+	ctx := context.Background()
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	service := &Service{
+		keycloakClient: mockClient,
+	}
+
+	tooManyRequests := &keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": []string{"0"}},
+		},
+	}
+	mockClient.EXPECT().GetUsersWithResponse(mock.Anything, "test-realm", mock.Anything, mock.Anything).
+		Return(tooManyRequests, nil).Times(maxRateLimitRetries + 1)
+
+	resp, err := service.getUsers(ctx, "test-realm", &keycloakClient.GetUsersParams{})
+
+	// The last 429 is handed back to the caller, which treats it as a regular non-200 response
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+}
+
+func TestGetUsers_TooManyRequestsContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	service := &Service{
+		keycloakClient: mockClient,
+	}
+
+	mockClient.EXPECT().GetUsersWithResponse(mock.Anything, "test-realm", mock.Anything, mock.Anything).
+		RunAndReturn(func(context.Context, string, *keycloakClient.GetUsersParams, ...keycloakClient.RequestEditorFn) (*keycloakClient.GetUsersResponse, error) {
+			cancel()
+			return &keycloakClient.GetUsersResponse{
+				HTTPResponse: &http.Response{
+					StatusCode: http.StatusTooManyRequests,
+					Header:     http.Header{"Retry-After": []string{"30"}},
+				},
+			}, nil
+		}).Once()
+
+	resp, err := service.getUsers(ctx, "test-realm", &keycloakClient.GetUsersParams{})
+
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+	assert.Contains(t, err.Error(), "context done")
+}
@@ -3,15 +3,17 @@ package keycloak
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
-	"github.com/coreos/go-oidc"
 	"github.com/platform-mesh/golang-commons/errors"
 	"github.com/platform-mesh/golang-commons/logger"
 	"golang.org/x/oauth2/clientcredentials"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 	"k8s.io/utils/ptr"
 
 	"github.com/platform-mesh/iam-service/pkg/cache"
@@ -20,27 +22,29 @@ import (
 	"github.com/platform-mesh/iam-service/pkg/graph"
 	keycloakClient "github.com/platform-mesh/iam-service/pkg/keycloak/client"
 	"github.com/platform-mesh/iam-service/pkg/metrics"
+	"github.com/platform-mesh/iam-service/pkg/redact"
 )
 
-// sanitizeEmail returns a sanitized version of the email for logging (first 3 chars + ***)
-// to avoid logging PII information
-func sanitizeEmail(email string) string {
-	if len(email) <= 3 {
-		return email
-	}
-	return email[:3] + "***"
-}
+// errKeycloakUnavailable marks user lookups that failed because Keycloak couldn't be reached or answered
+// with a server error. Best effort enrichment doesn't tolerate these, the other lookups would fail as well.
+var errKeycloakUnavailable = errors.Sentinel("keycloak unavailable")
+
+// ErrTruncated is returned when listing all users stopped at the configured page limit or timeout
+// before the last page. The users passed on until then are valid, but not complete.
+var ErrTruncated = errors.Sentinel("user listing truncated")
 
 type Service struct {
 	cfg            *config.ServiceConfig
+	httpClient     *http.Client
 	keycloakClient KeycloakClientInterface
 	userCache      *cache.UserCache
+	limiter        *rate.Limiter
 }
 
 func New(ctx context.Context, cfg *config.ServiceConfig) (*Service, error) {
 	log := logger.LoadLoggerFromContext(ctx)
 	issuer := fmt.Sprintf("%s/realms/master", cfg.Keycloak.BaseURL)
-	provider, err := oidc.NewProvider(ctx, issuer)
+	provider, err := newProvider(ctx, issuer, cfg.Keycloak.Discovery)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create OIDC provider for issuer %s", issuer)
 	}
@@ -56,6 +60,7 @@ func New(ctx context.Context, cfg *config.ServiceConfig) (*Service, error) {
 	kcClient, err := keycloakClient.NewClientWithResponses(
 		cfg.Keycloak.BaseURL,
 		keycloakClient.WithHTTPClient(httpClient),
+		keycloakClient.WithRequestEditorFn(appcontext.SetRequestIDHeader),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Keycloak client: %w", err)
@@ -64,33 +69,46 @@ func New(ctx context.Context, cfg *config.ServiceConfig) (*Service, error) {
 	// Initialize cache if enabled
 	var userCache *cache.UserCache
 	if cfg.Keycloak.Cache.Enabled {
-		userCache = cache.NewUserCache(cfg.Keycloak.Cache.TTL)
-		log.Info().Dur("ttl", cfg.Keycloak.Cache.TTL).Msg("Keycloak user cache enabled")
+		userCache = cache.NewUserCacheWithJitter(cfg.Keycloak.Cache.TTL, cfg.Keycloak.Cache.TTLJitter)
+		log.Info().Dur("ttl", cfg.Keycloak.Cache.TTL).Float64("ttlJitter", cfg.Keycloak.Cache.TTLJitter).Msg("Keycloak user cache enabled")
 	} else {
 		log.Info().Msg("Keycloak user cache disabled")
 	}
 
+	limiter := newRateLimiter(cfg.Keycloak.RateLimit)
+	if limiter != nil {
+		log.Info().Float64("rps", cfg.Keycloak.RateLimit.RPS).Int("burst", limiter.Burst()).Msg("Keycloak rate limiting enabled")
+	}
+
 	return &Service{
 		cfg:            cfg,
+		httpClient:     httpClient,
 		keycloakClient: kcClient,
 		userCache:      userCache,
+		limiter:        limiter,
 	}, nil
 }
 
+// Close releases the idle connections to Keycloak. Requests still in flight are not interrupted.
+func (s *Service) Close() error {
+	if s.httpClient != nil {
+		s.httpClient.CloseIdleConnections()
+	}
+	return nil
+}
+
 func (s *Service) UserByMail(ctx context.Context, userID string) (*graph.User, error) {
 	start := time.Now()
 	defer func() {
 		metrics.KeycloakDuration.WithLabelValues("user_by_mail").Observe(time.Since(start).Seconds())
 	}()
 
-	kctx, err := appcontext.GetKCPContext(ctx)
+	realm, err := appcontext.GetIDMTenant(ctx)
 	if err != nil {
 		metrics.KeycloakRequests.WithLabelValues("user_by_mail", "error").Inc()
 		return nil, errors.Wrap(err, "failed to get KCP user context")
 	}
 
-	realm := kctx.IDMTenant
-
 	// Try cache first if enabled
 	if s.userCache != nil {
 		if cachedUser := s.userCache.Get(realm, userID); cachedUser != nil {
@@ -122,14 +140,12 @@ func (s *Service) GetUsers(ctx context.Context) ([]*graph.User, error) {
 		metrics.KeycloakDuration.WithLabelValues("get_users").Observe(time.Since(start).Seconds())
 	}()
 
-	kctx, err := appcontext.GetKCPContext(ctx)
+	realm, err := appcontext.GetIDMTenant(ctx)
 	if err != nil {
 		metrics.KeycloakRequests.WithLabelValues("get_users", "error").Inc()
 		return nil, errors.Wrap(err, "failed to get KCP user context")
 	}
 
-	realm := kctx.IDMTenant
-
 	log.Debug().
 		Str("realm", realm).
 		Msg("Fetching all users from Keycloak")
@@ -150,6 +166,102 @@ func (s *Service) GetUsers(ctx context.Context) ([]*graph.User, error) {
 	return users, nil
 }
 
+// StreamAllUsers pages through all users of the realm from the context and calls fn once per page,
+// so that callers like exports don't have to hold every user in memory. Users are cached like in GetUsers
+// and pages that fail to load are skipped. An error returned by fn stops the iteration. Returns ErrTruncated
+// if the page limit or timeout was hit before all users were passed on.
+func (s *Service) StreamAllUsers(ctx context.Context, fn func(page []*graph.User) error) error {
+	start := time.Now()
+	defer func() {
+		metrics.KeycloakDuration.WithLabelValues("stream_all_users").Observe(time.Since(start).Seconds())
+	}()
+
+	realm, err := appcontext.GetIDMTenant(ctx)
+	if err != nil {
+		metrics.KeycloakRequests.WithLabelValues("stream_all_users", "error").Inc()
+		return errors.Wrap(err, "failed to get KCP user context")
+	}
+
+	if err := s.streamAllUsers(ctx, realm, fn); err != nil {
+		metrics.KeycloakRequests.WithLabelValues("stream_all_users", "error").Inc()
+		return err
+	}
+
+	metrics.KeycloakRequests.WithLabelValues("stream_all_users", "success").Inc()
+	return nil
+}
+
+// SearchUsers retrieves up to limit users matching the query from Keycloak, limit must be positive
+// Keycloak matches the query as prefix of the username, first name, last name or email
+// Found users are cached individually by email
+func (s *Service) SearchUsers(ctx context.Context, query string, limit int) ([]*graph.User, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+
+	start := time.Now()
+	defer func() {
+		metrics.KeycloakDuration.WithLabelValues("search_users").Observe(time.Since(start).Seconds())
+	}()
+
+	realm, err := appcontext.GetIDMTenant(ctx)
+	if err != nil {
+		metrics.KeycloakRequests.WithLabelValues("search_users", "error").Inc()
+		return nil, errors.Wrap(err, "failed to get KCP user context")
+	}
+
+	if limit <= 0 || limit > math.MaxInt32 {
+		metrics.KeycloakRequests.WithLabelValues("search_users", "error").Inc()
+		return nil, errors.New("invalid user search limit %d, must be between 1 and %d", limit, math.MaxInt32)
+	}
+
+	params := &keycloakClient.GetUsersParams{
+		Search:              &query,
+		Max:                 ptr.To(int32(limit)),
+		BriefRepresentation: ptr.To(true),
+		Exact:               ptr.To(false),
+	}
+
+	resp, err := s.getUsers(ctx, realm, params)
+	if err != nil {
+		metrics.KeycloakRequests.WithLabelValues("search_users", "error").Inc()
+		return nil, errors.Wrap(err, "failed to search users in realm %s", realm)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		metrics.KeycloakRequests.WithLabelValues("search_users", "error").Inc()
+		log.Error().Int("status_code", resp.StatusCode()).Str("realm", realm).Msg("Non-200 response from Keycloak")
+		return nil, errors.New("keycloak API returned status %d for user search in realm %s", resp.StatusCode(), realm)
+	}
+
+	users := make([]*graph.User, 0)
+	if resp.JSON200 != nil {
+		for _, user := range *resp.JSON200 {
+			if user.Id == nil || user.Email == nil {
+				continue
+			}
+
+			graphUser := &graph.User{
+				UserID:    *user.Id,
+				Email:     *user.Email,
+				FirstName: user.FirstName,
+				LastName:  user.LastName,
+			}
+			users = append(users, graphUser)
+
+			if s.userCache != nil {
+				s.userCache.Set(realm, *user.Email, graphUser)
+			}
+		}
+	}
+
+	log.Debug().
+		Int("user_count", len(users)).
+		Str("realm", realm).
+		Msg("Completed user search in Keycloak")
+
+	metrics.KeycloakRequests.WithLabelValues("search_users", "success").Inc()
+	return users, nil
+}
+
 // fetchUserFromKeycloak fetches a single user from Keycloak by email
 func (s *Service) fetchUserFromKeycloak(ctx context.Context, realm, email string) (*graph.User, error) {
 	log := logger.LoadLoggerFromContext(ctx)
@@ -163,15 +275,20 @@ func (s *Service) fetchUserFromKeycloak(ctx context.Context, realm, email string
 	}
 
 	// Query users using the generated client
-	resp, err := s.keycloakClient.GetUsersWithResponse(ctx, realm, params)
+	resp, err := s.getUsers(ctx, realm, params)
 	if err != nil { // coverage-ignore
-		log.Err(err).Str("email", sanitizeEmail(email)).Msg("Failed to query user")
-		return nil, errors.Wrap(err, "failed to query Keycloak API for user %s in realm %s", sanitizeEmail(email), realm)
+		log.Err(err).Str("email", redact.Email(email)).Msg("Failed to query user")
+		return nil, fmt.Errorf("%w: %w", errKeycloakUnavailable, errors.Wrap(err, "failed to query Keycloak API for user %s in realm %s", redact.Email(email), realm))
+	}
+
+	if resp.StatusCode() >= http.StatusInternalServerError {
+		log.Error().Int("status_code", resp.StatusCode()).Str("email", redact.Email(email)).Msg("Server error response from Keycloak")
+		return nil, errors.Wrap(errKeycloakUnavailable, "keycloak API returned status %d for user %s", resp.StatusCode(), redact.Email(email))
 	}
 
 	if resp.StatusCode() != http.StatusOK {
-		log.Error().Int("status_code", resp.StatusCode()).Str("email", sanitizeEmail(email)).Msg("Non-200 response from Keycloak")
-		return nil, errors.New("keycloak API returned status %d for user %s", resp.StatusCode(), sanitizeEmail(email))
+		log.Error().Int("status_code", resp.StatusCode()).Str("email", redact.Email(email)).Msg("Non-200 response from Keycloak")
+		return nil, errors.New("keycloak API returned status %d for user %s", resp.StatusCode(), redact.Email(email))
 	}
 
 	if resp.JSON200 == nil {
@@ -184,8 +301,8 @@ func (s *Service) fetchUserFromKeycloak(ctx context.Context, realm, email string
 	}
 
 	if len(users) != 1 {
-		log.Info().Str("email", sanitizeEmail(email)).Int("count", len(users)).Msg("unexpected user count")
-		return nil, errors.New("expected 1 user, got %d for email %s", len(users), sanitizeEmail(email))
+		log.Info().Str("email", redact.Email(email)).Int("count", len(users)).Msg("unexpected user count")
+		return nil, errors.New("expected 1 user, got %d for email %s", len(users), redact.Email(email))
 	}
 
 	user := users[0]
@@ -200,6 +317,12 @@ func (s *Service) fetchUserFromKeycloak(ctx context.Context, realm, email string
 }
 
 func (s *Service) GetUsersByEmails(ctx context.Context, emails []string) (map[string]*graph.User, error) {
+	return s.getUsersByEmails(ctx, emails, false)
+}
+
+// getUsersByEmails looks up the users with the given emails, using the cache when enabled.
+// In best effort mode users that fail to load are left out of the result instead of failing the lookup.
+func (s *Service) getUsersByEmails(ctx context.Context, emails []string, bestEffort bool) (map[string]*graph.User, error) {
 	log := logger.LoadLoggerFromContext(ctx)
 
 	start := time.Now()
@@ -211,13 +334,11 @@ func (s *Service) GetUsersByEmails(ctx context.Context, emails []string) (map[st
 		return map[string]*graph.User{}, nil
 	}
 
-	kctx, err := appcontext.GetKCPContext(ctx)
+	realm, err := appcontext.GetIDMTenant(ctx)
 	if err != nil {
 		metrics.KeycloakRequests.WithLabelValues("get_users_by_emails", "error").Inc()
 		return nil, errors.Wrap(err, "failed to get KCP user context")
 	}
-
-	realm := kctx.IDMTenant
 	result := make(map[string]*graph.User)
 
 	var missingEmails []string
@@ -245,7 +366,7 @@ func (s *Service) GetUsersByEmails(ctx context.Context, emails []string) (map[st
 
 	// Fetch missing users in parallel
 	if len(missingEmails) > 0 {
-		fetchedUsers, err := s.fetchUsersInParallel(ctx, realm, missingEmails)
+		fetchedUsers, err := s.fetchUsersInParallel(ctx, realm, missingEmails, bestEffort)
 		if err != nil {
 			metrics.KeycloakRequests.WithLabelValues("get_users_by_emails", "error").Inc()
 			return nil, errors.Wrap(err, "failed to fetch users in parallel for realm %s", realm)
@@ -272,22 +393,98 @@ func (s *Service) GetUsersByEmails(ctx context.Context, emails []string) (map[st
 	return result, nil
 }
 
+// WarmUserCache loads all users of the realm into the user cache, so that the profiles of users
+// listed after startup don't have to be fetched one by one. It does nothing if the cache is disabled.
+func (s *Service) WarmUserCache(ctx context.Context, realm string) error {
+	if s.userCache == nil {
+		return nil
+	}
+
+	var warmed int
+	err := s.streamAllUsers(ctx, realm, func(page []*graph.User) error {
+		warmed += len(page)
+		return nil
+	})
+	if errors.Is(err, ErrTruncated) {
+		logger.LoadLoggerFromContext(ctx).Warn().Err(err).Str("realm", realm).Msg("User cache only partially warmed up")
+	} else if err != nil { // coverage-ignore
+		return err
+	}
+
+	logger.LoadLoggerFromContext(ctx).Info().Str("realm", realm).Int("users", warmed).Msg("Warmed up user cache")
+	return nil
+}
+
 // fetchAllUsers retrieves all users from Keycloak using pagination
-// Caches individual users by email and uses best effort error handling
+// Caches individual users by email and uses best effort error handling: a truncated listing is logged
+// and the users collected so far are returned
 func (s *Service) fetchAllUsers(ctx context.Context, realm string) ([]*graph.User, error) {
+	allUsers := make([]*graph.User, 0)
+	err := s.streamAllUsers(ctx, realm, func(page []*graph.User) error {
+		allUsers = append(allUsers, page...)
+		return nil
+	})
+	if errors.Is(err, ErrTruncated) {
+		logger.LoadLoggerFromContext(ctx).Warn().Err(err).Str("realm", realm).Msg("Returning a truncated list of users")
+	} else if err != nil { // coverage-ignore
+		return nil, err
+	}
+
+	// Keycloak doesn't guarantee a stable page order, sort to keep results deterministic
+	sort.Slice(allUsers, func(i, j int) bool {
+		if allUsers[i].Email != allUsers[j].Email {
+			return allUsers[i].Email < allUsers[j].Email
+		}
+		return allUsers[i].UserID < allUsers[j].UserID
+	})
+
+	return allUsers, nil
+}
+
+// streamAllUsers pages through all users of the realm and passes the valid users of each page to fn
+// Caches individual users by email and uses best effort error handling: failed pages are skipped.
+// An error returned by fn stops the iteration and is returned as is. Hitting the page limit or the
+// timeout stops it with ErrTruncated.
+func (s *Service) streamAllUsers(ctx context.Context, realm string, fn func(page []*graph.User) error) error {
 	log := logger.LoadLoggerFromContext(ctx)
 
-	allUsers := make([]*graph.User, 0)
+	var usersCollected int
 	var failedPages []int
 	pageSize := s.cfg.Keycloak.PageSize
+	maxPages := s.cfg.Keycloak.MaxPages
 	var currentPage int = 0
 
+	if s.cfg.Keycloak.FetchAllTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Keycloak.FetchAllTimeout)
+		defer cancel()
+	}
+
 	log.Debug().
 		Str("realm", realm).
 		Int("page_size", pageSize).
+		Int("max_pages", maxPages).
 		Msg("Starting to fetch all users from Keycloak")
 
 	for {
+		// Guard against a Keycloak that never returns a partial page
+		if maxPages > 0 && currentPage >= maxPages {
+			log.Warn().
+				Int("max_pages", maxPages).
+				Int("users_collected", usersCollected).
+				Msg("Page limit reached, returning users collected so far")
+			return errors.Wrap(ErrTruncated, "page limit of %d reached after %d users", maxPages, usersCollected)
+		}
+
+		if ctx.Err() != nil {
+			log.Warn().
+				Err(ctx.Err()).
+				Int("page", currentPage).
+				Int("users_collected", usersCollected).
+				Msg("Deadline reached while fetching users, returning users collected so far")
+			return fmt.Errorf("%w: %w", ErrTruncated, errors.Wrap(ctx.Err(), "stopped at page %d after %d users", currentPage, usersCollected))
+		}
+
 		// Calculate offset for current page
 		first := currentPage * pageSize
 
@@ -307,7 +504,7 @@ func (s *Service) fetchAllUsers(ctx context.Context, realm string) ([]*graph.Use
 			Msg("Fetching users page")
 
 		// Query users for current page
-		resp, err := s.keycloakClient.GetUsersWithResponse(ctx, realm, params)
+		resp, err := s.getUsers(ctx, realm, params)
 		if err != nil {
 			log.Err(err).
 				Int("page", currentPage).
@@ -344,6 +541,7 @@ func (s *Service) fetchAllUsers(ctx context.Context, realm string) ([]*graph.Use
 			Msg("Processing users from page")
 
 		// Process users from current page
+		pageUsers := make([]*graph.User, 0, len(users))
 		for _, user := range users {
 			if user.Id == nil || user.Email == nil {
 				log.Warn().
@@ -359,7 +557,7 @@ func (s *Service) fetchAllUsers(ctx context.Context, realm string) ([]*graph.Use
 				LastName:  user.LastName,
 			}
 
-			allUsers = append(allUsers, graphUser)
+			pageUsers = append(pageUsers, graphUser)
 
 			// Cache individual user by email if cache is enabled
 			if s.userCache != nil {
@@ -367,6 +565,13 @@ func (s *Service) fetchAllUsers(ctx context.Context, realm string) ([]*graph.Use
 			}
 		}
 
+		if len(pageUsers) > 0 {
+			if err := fn(pageUsers); err != nil {
+				return err
+			}
+			usersCollected += len(pageUsers)
+		}
+
 		// If we got fewer users than page size, we've reached the end
 		if len(users) < int(pageSize) {
 			log.Debug().
@@ -380,7 +585,7 @@ func (s *Service) fetchAllUsers(ctx context.Context, realm string) ([]*graph.Use
 	}
 
 	log.Debug().
-		Int("total_users", len(allUsers)).
+		Int("total_users", usersCollected).
 		Int("failed_pages", len(failedPages)).
 		Int("pages_processed", currentPage).
 		Msg("Completed fetching all users from Keycloak")
@@ -391,14 +596,21 @@ func (s *Service) fetchAllUsers(ctx context.Context, realm string) ([]*graph.Use
 			Msg("Some pages failed to fetch, returning partial results")
 	}
 
-	return allUsers, nil
+	return nil
 }
 
 // fetchUsersInParallel fetches multiple users from Keycloak in parallel using errgroup
-// Fails fast on the first encountered error
-func (s *Service) fetchUsersInParallel(ctx context.Context, realm string, emails []string) (map[string]*graph.User, error) {
+// Fails fast on the first encountered error, unless bestEffort is set, in which case users that fail
+// to load are logged and left out of the result. Even then it fails if Keycloak is unavailable, so an
+// outage isn't mistaken for missing profiles. At most Keycloak.MaxConcurrency requests are in flight.
+func (s *Service) fetchUsersInParallel(ctx context.Context, realm string, emails []string, bestEffort bool) (map[string]*graph.User, error) {
+	log := logger.LoadLoggerFromContext(ctx)
+
 	// Use errgroup with context for fail-fast behavior
 	g, gCtx := errgroup.WithContext(ctx)
+	if s.cfg != nil && s.cfg.Keycloak.MaxConcurrency > 0 {
+		g.SetLimit(s.cfg.Keycloak.MaxConcurrency)
+	}
 
 	// Thread-safe map to store results
 	var mu sync.Mutex
@@ -409,10 +621,17 @@ func (s *Service) fetchUsersInParallel(ctx context.Context, realm string, emails
 		email := email // capture loop variable
 		g.Go(func() error {
 			user, err := s.fetchUserFromKeycloak(gCtx, realm, email)
+			if err != nil && bestEffort && !errors.Is(err, errKeycloakUnavailable) {
+				log.Warn().
+					Err(err).
+					Str("email", redact.Email(email)).
+					Msg("Failed to fetch user, continuing without it")
+				return nil
+			}
 			if err != nil {
 				// Return error immediately to trigger fail-fast behavior
 				// Only log first few characters of email to avoid PII exposure
-				return fmt.Errorf("failed to fetch user %s: %w", sanitizeEmail(email), err)
+				return fmt.Errorf("failed to fetch user %s: %w", redact.Email(email), err)
 			}
 
 			mu.Lock()
@@ -441,8 +660,41 @@ func (s *Service) EnrichUserRoles(ctx context.Context, userRoles []*graph.UserRo
 		metrics.KeycloakDuration.WithLabelValues("enrich_user_roles").Observe(time.Since(start).Seconds())
 	}()
 
+	_, err := s.enrichUserRoles(ctx, userRoles, "enrich_user_roles")
+	return err
+}
+
+// RefreshUserRoles drops the cached Keycloak profiles of the given users and enriches them again,
+// so that profile updates in Keycloak show up before the cache entries expire.
+// Returns the number of users that were found in Keycloak.
+func (s *Service) RefreshUserRoles(ctx context.Context, userRoles []*graph.UserRoles) (int, error) {
+	start := time.Now()
+	defer func() {
+		metrics.KeycloakDuration.WithLabelValues("refresh_user_roles").Observe(time.Since(start).Seconds())
+	}()
+
+	if s.userCache != nil && len(userRoles) > 0 {
+		realm, err := appcontext.GetIDMTenant(ctx)
+		if err != nil {
+			metrics.KeycloakRequests.WithLabelValues("refresh_user_roles", "error").Inc()
+			return 0, errors.Wrap(err, "failed to get KCP user context")
+		}
+
+		for _, userRole := range userRoles {
+			if userRole.User != nil && userRole.User.Email != "" {
+				s.userCache.Delete(realm, userRole.User.Email)
+			}
+		}
+	}
+
+	return s.enrichUserRoles(ctx, userRoles, "refresh_user_roles")
+}
+
+// enrichUserRoles updates the user roles in-place with the users' Keycloak profiles
+// and returns the number of user roles that were enriched
+func (s *Service) enrichUserRoles(ctx context.Context, userRoles []*graph.UserRoles, operation string) (int, error) {
 	if len(userRoles) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	// Extract unique email addresses from user roles
@@ -459,17 +711,19 @@ func (s *Service) EnrichUserRoles(ctx context.Context, userRoles []*graph.UserRo
 	}
 
 	if len(emails) == 0 {
-		return nil
+		return 0, nil
 	}
 
-	// Batch call to get all users at once
-	userMap, err := s.GetUsersByEmails(ctx, emails)
+	// Batch call to get all users at once. Enrichment is best effort: users that fail
+	// to load keep the data from OpenFGA instead of failing the whole list.
+	userMap, err := s.getUsersByEmails(ctx, emails, true)
 	if err != nil {
-		metrics.KeycloakRequests.WithLabelValues("enrich_user_roles", "error").Inc()
-		return errors.Wrap(err, "failed to get users by emails for enrichment")
+		metrics.KeycloakRequests.WithLabelValues(operation, "error").Inc()
+		return 0, errors.Wrap(err, "failed to get users by emails for enrichment")
 	}
 
 	// Update user roles with Keycloak data using the lookup map
+	var enriched int
 	for _, userRole := range userRoles {
 		if userRole.User != nil && userRole.User.Email != "" {
 			if keycloakUser, exists := userMap[userRole.User.Email]; exists {
@@ -478,10 +732,11 @@ func (s *Service) EnrichUserRoles(ctx context.Context, userRoles []*graph.UserRo
 				userRole.User.FirstName = keycloakUser.FirstName
 				userRole.User.LastName = keycloakUser.LastName
 				// Email is already set from OpenFGA
+				enriched++
 			}
 		}
 	}
 
-	metrics.KeycloakRequests.WithLabelValues("enrich_user_roles", "success").Inc()
-	return nil
+	metrics.KeycloakRequests.WithLabelValues(operation, "success").Inc()
+	return enriched, nil
 }
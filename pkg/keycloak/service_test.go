@@ -2,12 +2,15 @@ package keycloak
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"k8s.io/utils/ptr"
 
 	"github.com/platform-mesh/iam-service/pkg/cache"
@@ -136,6 +139,21 @@ func TestUserByMail_NoKcpContext(t *testing.T) {
 	assert.Contains(t, err.Error(), "kcp user context")
 }
 
+func TestService_EmptyIDMTenant(t *testing.T) {
+	// No Keycloak request may be sent for an empty realm
+	ctx := appcontext.SetKCPContext(context.Background(), appcontext.KCPContext{OrganizationName: "test-org"})
+	service := &Service{keycloakClient: mocks.NewKeycloakClientInterface(t)}
+
+	_, err := service.UserByMail(ctx, "test@example.com")
+	assert.ErrorContains(t, err, "IDM tenant must not be empty")
+
+	_, err = service.GetUsersByEmails(ctx, []string{"test@example.com"})
+	assert.ErrorContains(t, err, "IDM tenant must not be empty")
+
+	_, err = service.SearchUsers(ctx, "test", 10)
+	assert.ErrorContains(t, err, "IDM tenant must not be empty")
+}
+
 func TestEnrichUserRoles_Success(t *testing.T) {
 	// Setup
 	ctx := context.Background()
@@ -226,6 +244,197 @@ func TestEnrichUserRoles_Success(t *testing.T) {
 	assert.Equal(t, lastName2, *userRoles[1].User.LastName)
 }
 
+func TestEnrichUserRoles_RespectsMaxConcurrency(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	const maxConcurrency = 3
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	service := &Service{
+		cfg:            &config.ServiceConfig{Keycloak: config.KeycloakConfig{MaxConcurrency: maxConcurrency}},
+		keycloakClient: mockClient,
+	}
+
+	var inFlight, maxInFlight atomic.Int32
+	mockClient.EXPECT().GetUsersWithResponse(mock.Anything, "test-realm", mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, _ string, params *keycloakClient.GetUsersParams, _ ...keycloakClient.RequestEditorFn) (*keycloakClient.GetUsersResponse, error) {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				highest := maxInFlight.Load()
+				if current <= highest || maxInFlight.CompareAndSwap(highest, current) {
+					break
+				}
+			}
+			// Keep the request open long enough for others to pile up
+			time.Sleep(10 * time.Millisecond)
+
+			return &keycloakClient.GetUsersResponse{
+				HTTPResponse: &http.Response{StatusCode: 200},
+				JSON200: &[]keycloakClient.UserRepresentation{
+					{Id: ptr.To("id-" + *params.Email), Email: params.Email},
+				},
+			}, nil
+		}).Times(20)
+
+	userRoles := make([]*graph.UserRoles, 20)
+	for i := range userRoles {
+		userRoles[i] = &graph.UserRoles{User: &graph.User{Email: fmt.Sprintf("user%d@example.com", i)}}
+	}
+
+	err := service.EnrichUserRoles(ctx, userRoles)
+
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(maxConcurrency))
+	for _, ur := range userRoles {
+		assert.Equal(t, "id-"+ur.User.Email, ur.User.UserID)
+	}
+}
+
+func TestEnrichUserRoles_PartialFailure(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	service := &Service{
+		keycloakClient: mockClient,
+	}
+
+	mockClient.EXPECT().GetUsersWithResponse(
+		mock.Anything,
+		"test-realm",
+		mock.MatchedBy(func(params *keycloakClient.GetUsersParams) bool {
+			return params != nil && params.Email != nil && *params.Email == "user1@example.com"
+		}),
+		mock.Anything,
+	).Return(&keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200: &[]keycloakClient.UserRepresentation{
+			{Id: ptr.To("keycloak-user-1"), Email: ptr.To("user1@example.com"), FirstName: ptr.To("John")},
+		},
+	}, nil)
+
+	mockClient.EXPECT().GetUsersWithResponse(
+		mock.Anything,
+		"test-realm",
+		mock.MatchedBy(func(params *keycloakClient.GetUsersParams) bool {
+			return params != nil && params.Email != nil && *params.Email == "user2@example.com"
+		}),
+		mock.Anything,
+	).Return(&keycloakClient.GetUsersResponse{
+		// An ambiguous email is a miss of this user only
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200: &[]keycloakClient.UserRepresentation{
+			{Id: ptr.To("keycloak-user-2"), Email: ptr.To("user2@example.com")},
+			{Id: ptr.To("keycloak-user-3"), Email: ptr.To("user2@example.com")},
+		},
+	}, nil)
+
+	failedRoles := []*graph.Role{{ID: "member"}}
+	userRoles := []*graph.UserRoles{
+		{User: &graph.User{Email: "user1@example.com"}},
+		{User: &graph.User{Email: "user2@example.com"}, Roles: failedRoles},
+	}
+
+	err := service.EnrichUserRoles(ctx, userRoles)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "keycloak-user-1", userRoles[0].User.UserID)
+	assert.Equal(t, "John", *userRoles[0].User.FirstName)
+
+	// The user that failed to load keeps the data from OpenFGA
+	assert.Equal(t, "user2@example.com", userRoles[1].User.Email)
+	assert.Empty(t, userRoles[1].User.UserID)
+	assert.Nil(t, userRoles[1].User.FirstName)
+	assert.Equal(t, failedRoles, userRoles[1].Roles)
+}
+
+func TestEnrichUserRoles_CachedUserAndFailedLookup(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	userCache := cache.NewUserCache(time.Hour)
+	userCache.Set("test-realm", "user1@example.com", &graph.User{UserID: "keycloak-user-1", Email: "user1@example.com"})
+	service := &Service{
+		keycloakClient: mockClient,
+		userCache:      userCache,
+	}
+
+	// The only lookup that is sent fails, which must not fail the users served from the cache
+	mockClient.EXPECT().GetUsersWithResponse(
+		mock.Anything,
+		"test-realm",
+		mock.MatchedBy(func(params *keycloakClient.GetUsersParams) bool {
+			return params != nil && params.Email != nil && *params.Email == "user2@example.com"
+		}),
+		mock.Anything,
+	).Return(&keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 403},
+	}, nil).Once()
+
+	failedRoles := []*graph.Role{{ID: "member"}}
+	userRoles := []*graph.UserRoles{
+		{User: &graph.User{Email: "user1@example.com"}},
+		{User: &graph.User{Email: "user2@example.com"}, Roles: failedRoles},
+	}
+
+	err := service.EnrichUserRoles(ctx, userRoles)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "keycloak-user-1", userRoles[0].User.UserID)
+	assert.Empty(t, userRoles[1].User.UserID)
+	assert.Equal(t, failedRoles, userRoles[1].Roles)
+}
+
+func TestEnrichUserRoles_KeycloakUnavailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *keycloakClient.GetUsersResponse
+		err      error
+	}{
+		{
+			name:     "server error",
+			response: &keycloakClient.GetUsersResponse{HTTPResponse: &http.Response{StatusCode: 503}},
+		},
+		{
+			name: "transport error",
+			err:  assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := appcontext.SetKCPContext(context.Background(), appcontext.KCPContext{
+				IDMTenant: "test-realm",
+			})
+
+			mockClient := mocks.NewKeycloakClientInterface(t)
+			service := &Service{
+				keycloakClient: mockClient,
+			}
+
+			mockClient.EXPECT().GetUsersWithResponse(mock.Anything, "test-realm", mock.Anything, mock.Anything).
+				Return(tt.response, tt.err).Maybe()
+
+			userRoles := []*graph.UserRoles{
+				{User: &graph.User{Email: "user1@example.com"}},
+				{User: &graph.User{Email: "user2@example.com"}},
+			}
+
+			err := service.EnrichUserRoles(ctx, userRoles)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
 func TestEnrichUserRoles_EmptySlice(t *testing.T) {
 	// Setup
 	service := &Service{}
@@ -243,6 +452,88 @@ func TestEnrichUserRoles_EmptySlice(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestRefreshUserRoles_InvalidatesCacheAndReenriches(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	userCache := cache.NewUserCache(time.Hour)
+	service := &Service{
+		keycloakClient: mockClient,
+		userCache:      userCache,
+	}
+
+	// Stale profile from before the Keycloak update
+	userCache.Set("test-realm", "user1@example.com", &graph.User{
+		UserID:    "keycloak-user-1",
+		Email:     "user1@example.com",
+		FirstName: ptr.To("Old"),
+	})
+	// Unrelated users stay cached
+	userCache.Set("test-realm", "other@example.com", &graph.User{UserID: "other", Email: "other@example.com"})
+
+	mockClient.EXPECT().GetUsersWithResponse(
+		mock.Anything,
+		"test-realm",
+		mock.MatchedBy(func(params *keycloakClient.GetUsersParams) bool {
+			return params != nil && params.Email != nil && *params.Email == "user1@example.com"
+		}),
+		mock.Anything,
+	).Return(&keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200: &[]keycloakClient.UserRepresentation{
+			{Id: ptr.To("keycloak-user-1"), Email: ptr.To("user1@example.com"), FirstName: ptr.To("New")},
+		},
+	}, nil).Once()
+	mockClient.EXPECT().GetUsersWithResponse(
+		mock.Anything,
+		"test-realm",
+		mock.MatchedBy(func(params *keycloakClient.GetUsersParams) bool {
+			return params != nil && params.Email != nil && *params.Email == "deleted@example.com"
+		}),
+		mock.Anything,
+	).Return(&keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200:      &[]keycloakClient.UserRepresentation{},
+	}, nil).Once()
+
+	userRoles := []*graph.UserRoles{
+		{User: &graph.User{Email: "user1@example.com"}},
+		{User: &graph.User{Email: "deleted@example.com"}},
+	}
+
+	count, err := service.RefreshUserRoles(ctx, userRoles)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "New", *userRoles[0].User.FirstName)
+	assert.Equal(t, "New", *userCache.Get("test-realm", "user1@example.com").FirstName)
+	assert.NotNil(t, userCache.Get("test-realm", "other@example.com"))
+}
+
+func TestRefreshUserRoles_NoKCPContext(t *testing.T) {
+	service := &Service{userCache: cache.NewUserCache(time.Hour)}
+
+	count, err := service.RefreshUserRoles(context.Background(), []*graph.UserRoles{
+		{User: &graph.User{Email: "user1@example.com"}},
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, count)
+	assert.Contains(t, err.Error(), "KCP user context")
+}
+
+func TestRefreshUserRoles_EmptySlice(t *testing.T) {
+	service := &Service{userCache: cache.NewUserCache(time.Hour)}
+
+	count, err := service.RefreshUserRoles(context.Background(), nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
 func TestNew_InvalidConfig(t *testing.T) {
 	// Test with invalid configuration to ensure error handling
 	ctx := context.Background()
@@ -616,12 +907,12 @@ func TestFetchUsersInParallel_WithErrors(t *testing.T) {
 	).Return(errorResponse, nil)
 
 	emails := []string{userEmail1, "error@example.com"}
-	result, err := service.fetchUsersInParallel(ctx, "test-realm", emails)
+	result, err := service.fetchUsersInParallel(ctx, "test-realm", emails, false)
 
 	// Should return error on first failure (fail-fast behavior)
 	assert.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "failed to fetch user err***")
+	assert.Contains(t, err.Error(), "failed to fetch user err***@example.com")
 }
 
 func TestNew_InvalidClientSecret(t *testing.T) {
@@ -751,6 +1042,43 @@ func TestFetchAllUsers_SinglePage(t *testing.T) {
 	assert.Equal(t, userID2, cachedUser2.UserID)
 }
 
+func TestWarmUserCache(t *testing.T) {
+	ctx := context.Background()
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	userCache := cache.NewUserCache(5 * time.Minute)
+	service := &Service{
+		keycloakClient: mockClient,
+		userCache:      userCache,
+		cfg:            &config.ServiceConfig{Keycloak: config.KeycloakConfig{PageSize: 10}},
+	}
+
+	mockClient.EXPECT().GetUsersWithResponse(mock.Anything, "test-realm", mock.Anything, mock.Anything).Return(&keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200: &[]keycloakClient.UserRepresentation{
+			{Id: ptr.To("user-1"), Email: ptr.To("user1@example.com")},
+			{Id: ptr.To("user-2"), Email: ptr.To("user2@example.com")},
+		},
+	}, nil).Once()
+
+	err := service.WarmUserCache(ctx, "test-realm")
+
+	assert.NoError(t, err)
+	require.NotNil(t, userCache.Get("test-realm", "user1@example.com"))
+	assert.Equal(t, "user-1", userCache.Get("test-realm", "user1@example.com").UserID)
+	require.NotNil(t, userCache.Get("test-realm", "user2@example.com"))
+	assert.Equal(t, "user-2", userCache.Get("test-realm", "user2@example.com").UserID)
+}
+
+func TestWarmUserCache_CacheDisabled(t *testing.T) {
+	// No Keycloak request is expected without a cache to fill
+	service := &Service{keycloakClient: mocks.NewKeycloakClientInterface(t)}
+
+	err := service.WarmUserCache(context.Background(), "test-realm")
+
+	assert.NoError(t, err)
+}
+
 func TestFetchAllUsers_MultiplePages(t *testing.T) {
 	// Test fetching all users across multiple pages
 	ctx := context.Background()
@@ -1034,3 +1362,430 @@ func TestGetUsers_NoKCPContext(t *testing.T) {
 	assert.Nil(t, result)
 	assert.Contains(t, err.Error(), "kcp user context")
 }
+
+func TestSearchUsers_Success(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	userCache := cache.NewUserCache(5 * time.Minute)
+	service := &Service{
+		keycloakClient: mockClient,
+		userCache:      userCache,
+	}
+
+	userID1 := "user-1"
+	userEmail1 := "jane@example.com"
+	userID2 := "user-2"
+	userEmail2 := "janet@example.com"
+	users := []keycloakClient.UserRepresentation{
+		{Id: &userID1, Email: &userEmail1},
+		{Id: &userID2, Email: &userEmail2},
+		// Users without email can't be referenced in FGA and are skipped
+		{Id: ptr.To("user-3")},
+	}
+
+	mockClient.EXPECT().GetUsersWithResponse(
+		ctx,
+		"test-realm",
+		mock.MatchedBy(func(params *keycloakClient.GetUsersParams) bool {
+			return params != nil &&
+				params.Search != nil && *params.Search == "jan" &&
+				params.Max != nil && *params.Max == int32(5) &&
+				params.Exact != nil && !*params.Exact
+		}),
+		mock.Anything,
+	).Return(&keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200:      &users,
+	}, nil)
+
+	result, err := service.SearchUsers(ctx, "jan", 5)
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, userEmail1, result[0].Email)
+	assert.Equal(t, userEmail2, result[1].Email)
+
+	// Verify users were cached
+	assert.NotNil(t, userCache.Get("test-realm", userEmail1))
+	assert.NotNil(t, userCache.Get("test-realm", userEmail2))
+}
+
+func TestSearchUsers_EmptyResult(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	service := &Service{
+		keycloakClient: mockClient,
+	}
+
+	mockClient.EXPECT().GetUsersWithResponse(ctx, "test-realm", mock.Anything, mock.Anything).
+		Return(&keycloakClient.GetUsersResponse{
+			HTTPResponse: &http.Response{StatusCode: 200},
+			JSON200:      &[]keycloakClient.UserRepresentation{},
+		}, nil)
+
+	result, err := service.SearchUsers(ctx, "nobody", 10)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+}
+
+func TestSearchUsers_Non200Status(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	service := &Service{
+		keycloakClient: mockClient,
+	}
+
+	mockClient.EXPECT().GetUsersWithResponse(ctx, "test-realm", mock.Anything, mock.Anything).
+		Return(&keycloakClient.GetUsersResponse{
+			HTTPResponse: &http.Response{StatusCode: 500},
+		}, nil)
+
+	result, err := service.SearchUsers(ctx, "jan", 10)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "status 500")
+}
+
+func TestSearchUsers_InvalidLimit(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	// No request is sent for a limit Keycloak can't honour
+	service := &Service{
+		keycloakClient: mocks.NewKeycloakClientInterface(t),
+	}
+
+	for _, limit := range []int{0, -1} {
+		result, err := service.SearchUsers(ctx, "jan", limit)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "invalid user search limit")
+	}
+}
+
+func TestSearchUsers_NoKCPContext(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{}
+
+	result, err := service.SearchUsers(ctx, "jan", 10)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "kcp user context")
+}
+
+func TestFetchAllUsers_SortedByEmail(t *testing.T) {
+	ctx := context.Background()
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	cfg := &config.ServiceConfig{
+		Keycloak: config.KeycloakConfig{
+			PageSize: 2,
+		},
+	}
+	service := &Service{
+		keycloakClient: mockClient,
+		cfg:            cfg,
+	}
+
+	// Pages arrive out of email order, the second page even contains a duplicate email
+	page1Users := []keycloakClient.UserRepresentation{
+		{Id: ptr.To("user-c"), Email: ptr.To("charlie@example.com")},
+		{Id: ptr.To("user-a"), Email: ptr.To("alice@example.com")},
+	}
+	page2Users := []keycloakClient.UserRepresentation{
+		{Id: ptr.To("user-b2"), Email: ptr.To("bob@example.com")},
+		{Id: ptr.To("user-b1"), Email: ptr.To("bob@example.com")},
+	}
+
+	mockClient.EXPECT().GetUsersWithResponse(
+		ctx,
+		"test-realm",
+		mock.MatchedBy(func(params *keycloakClient.GetUsersParams) bool {
+			return params != nil && params.First != nil && *params.First == int32(0)
+		}),
+		mock.Anything,
+	).Return(&keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200:      &page1Users,
+	}, nil)
+
+	mockClient.EXPECT().GetUsersWithResponse(
+		ctx,
+		"test-realm",
+		mock.MatchedBy(func(params *keycloakClient.GetUsersParams) bool {
+			return params != nil && params.First != nil && *params.First == int32(2)
+		}),
+		mock.Anything,
+	).Return(&keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200:      &page2Users,
+	}, nil)
+
+	mockClient.EXPECT().GetUsersWithResponse(
+		ctx,
+		"test-realm",
+		mock.MatchedBy(func(params *keycloakClient.GetUsersParams) bool {
+			return params != nil && params.First != nil && *params.First == int32(4)
+		}),
+		mock.Anything,
+	).Return(&keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200:      &[]keycloakClient.UserRepresentation{},
+	}, nil)
+
+	result, err := service.fetchAllUsers(ctx, "test-realm")
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 4)
+	assert.Equal(t, "user-a", result[0].UserID)
+	// Users sharing an email are ordered by user ID
+	assert.Equal(t, "user-b1", result[1].UserID)
+	assert.Equal(t, "user-b2", result[2].UserID)
+	assert.Equal(t, "user-c", result[3].UserID)
+}
+
+func TestFetchAllUsers_StopsAtMaxPages(t *testing.T) {
+	ctx := context.Background()
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	cfg := &config.ServiceConfig{
+		Keycloak: config.KeycloakConfig{
+			PageSize: 1,
+			MaxPages: 3,
+		},
+	}
+	service := &Service{
+		keycloakClient: mockClient,
+		cfg:            cfg,
+	}
+
+	// Every page is full, so pagination would never end on its own
+	mockClient.EXPECT().GetUsersWithResponse(ctx, "test-realm", mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, _ string, params *keycloakClient.GetUsersParams, _ ...keycloakClient.RequestEditorFn) (*keycloakClient.GetUsersResponse, error) {
+			id := fmt.Sprintf("user-%d", *params.First)
+			email := fmt.Sprintf("user%d@example.com", *params.First)
+			return &keycloakClient.GetUsersResponse{
+				HTTPResponse: &http.Response{StatusCode: 200},
+				JSON200:      &[]keycloakClient.UserRepresentation{{Id: &id, Email: &email}},
+			}, nil
+		}).Times(3)
+
+	result, err := service.fetchAllUsers(ctx, "test-realm")
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+}
+
+func TestFetchAllUsers_DeadlineReached(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	cfg := &config.ServiceConfig{
+		Keycloak: config.KeycloakConfig{
+			PageSize:        10,
+			FetchAllTimeout: time.Minute,
+		},
+	}
+	service := &Service{
+		keycloakClient: mockClient,
+		cfg:            cfg,
+	}
+
+	// No request is sent once the context is done
+	result, err := service.fetchAllUsers(ctx, "test-realm")
+
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+// expectUsersPage mocks the Keycloak response for the page starting at first
+func expectUsersPage(mockClient *mocks.KeycloakClientInterface, first int32, resp *keycloakClient.GetUsersResponse) {
+	mockClient.EXPECT().GetUsersWithResponse(
+		mock.Anything,
+		"test-realm",
+		mock.MatchedBy(func(params *keycloakClient.GetUsersParams) bool {
+			return params != nil && params.First != nil && *params.First == first
+		}),
+		mock.Anything,
+	).Return(resp, nil).Once()
+}
+
+func TestStreamAllUsers_CallsFnPerPage(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	userCache := cache.NewUserCache(time.Hour)
+	service := &Service{
+		keycloakClient: mockClient,
+		cfg:            &config.ServiceConfig{Keycloak: config.KeycloakConfig{PageSize: 2}},
+		userCache:      userCache,
+	}
+
+	expectUsersPage(mockClient, 0, &keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200: &[]keycloakClient.UserRepresentation{
+			{Id: ptr.To("user-1"), Email: ptr.To("user1@example.com")},
+			{Id: ptr.To("user-2"), Email: ptr.To("user2@example.com")},
+		},
+	})
+	// A failing page is skipped like in GetUsers
+	expectUsersPage(mockClient, 2, &keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 500},
+	})
+	expectUsersPage(mockClient, 4, &keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200: &[]keycloakClient.UserRepresentation{
+			{Id: ptr.To("user-3"), Email: ptr.To("user3@example.com")},
+		},
+	})
+
+	var pages [][]string
+	err := service.StreamAllUsers(ctx, func(page []*graph.User) error {
+		var ids []string
+		for _, user := range page {
+			ids = append(ids, user.UserID)
+		}
+		pages = append(pages, ids)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"user-1", "user-2"}, {"user-3"}}, pages)
+	assert.NotNil(t, userCache.Get("test-realm", "user3@example.com"))
+}
+
+func TestStreamAllUsers_FnErrorStopsIteration(t *testing.T) {
+	ctx := context.Background()
+	ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+		IDMTenant: "test-realm",
+	})
+
+	mockClient := mocks.NewKeycloakClientInterface(t)
+	service := &Service{
+		keycloakClient: mockClient,
+		cfg:            &config.ServiceConfig{Keycloak: config.KeycloakConfig{PageSize: 2}},
+	}
+
+	// Only the first page must be requested
+	expectUsersPage(mockClient, 0, &keycloakClient.GetUsersResponse{
+		HTTPResponse: &http.Response{StatusCode: 200},
+		JSON200: &[]keycloakClient.UserRepresentation{
+			{Id: ptr.To("user-1"), Email: ptr.To("user1@example.com")},
+			{Id: ptr.To("user-2"), Email: ptr.To("user2@example.com")},
+		},
+	})
+
+	calls := 0
+	err := service.StreamAllUsers(ctx, func(page []*graph.User) error {
+		calls++
+		return assert.AnError
+	})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 1, calls)
+}
+
+func TestStreamAllUsers_NoKCPContext(t *testing.T) {
+	service := &Service{}
+
+	err := service.StreamAllUsers(context.Background(), func(page []*graph.User) error {
+		t.Fatal("fn must not be called")
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "KCP user context")
+}
+
+func TestStreamAllUsers_Truncated(t *testing.T) {
+	tests := []struct {
+		name          string
+		keycloak      config.KeycloakConfig
+		canceled      bool
+		expectedPages int
+		expectedError string
+	}{
+		{
+			name:          "page limit reached",
+			keycloak:      config.KeycloakConfig{PageSize: 1, MaxPages: 2},
+			expectedPages: 2,
+			expectedError: "page limit of 2 reached after 2 users",
+		},
+		{
+			name:          "deadline reached",
+			keycloak:      config.KeycloakConfig{PageSize: 1, FetchAllTimeout: time.Minute},
+			canceled:      true,
+			expectedError: "stopped at page 0 after 0 users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ctx = appcontext.SetKCPContext(ctx, appcontext.KCPContext{
+				IDMTenant: "test-realm",
+			})
+			if tt.canceled {
+				cancel()
+			}
+
+			mockClient := mocks.NewKeycloakClientInterface(t)
+			service := &Service{
+				keycloakClient: mockClient,
+				cfg:            &config.ServiceConfig{Keycloak: tt.keycloak},
+			}
+
+			// Every page is full, so pagination would never end on its own
+			if tt.expectedPages > 0 {
+				mockClient.EXPECT().GetUsersWithResponse(mock.Anything, "test-realm", mock.Anything, mock.Anything).
+					RunAndReturn(func(_ context.Context, _ string, params *keycloakClient.GetUsersParams, _ ...keycloakClient.RequestEditorFn) (*keycloakClient.GetUsersResponse, error) {
+						id := fmt.Sprintf("user-%d", *params.First)
+						email := fmt.Sprintf("user%d@example.com", *params.First)
+						return &keycloakClient.GetUsersResponse{
+							HTTPResponse: &http.Response{StatusCode: 200},
+							JSON200:      &[]keycloakClient.UserRepresentation{{Id: &id, Email: &email}},
+						}, nil
+					}).Times(tt.expectedPages)
+			}
+
+			pages := 0
+			err := service.StreamAllUsers(ctx, func(page []*graph.User) error {
+				pages++
+				return nil
+			})
+
+			assert.ErrorIs(t, err, ErrTruncated)
+			assert.ErrorContains(t, err, tt.expectedError)
+			assert.Equal(t, tt.expectedPages, pages)
+		})
+	}
+}
+
+func TestService_Close(t *testing.T) {
+	assert.NoError(t, (&Service{}).Close())
+	assert.NoError(t, (&Service{httpClient: &http.Client{}}).Close())
+}
@@ -47,21 +47,35 @@ func (m *Middleware) SetKCPUserContext() func(http.Handler) http.Handler {
 			tokenInfo, err := pmcontext.GetWebTokenFromContext(ctx)
 			if err != nil {
 				log.Debug().Err(err).Msg("No Token info found in context")
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				writeProblem(w, Problem{
+					Type:   ProblemTypeUnauthenticated,
+					Title:  http.StatusText(http.StatusUnauthorized),
+					Status: http.StatusUnauthorized,
+					Detail: "the request carries no valid token",
+				})
 				return
 			}
 
 			idmTenant, err := m.tenantRetriever.GetIDMTenant(tokenInfo.Issuer)
 			if err != nil {
 				log.Error().Err(err).Msg("Error while retrieving realm info")
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				writeProblem(w, Problem{
+					Type:   ProblemTypeTenantResolution,
+					Title:  "Failed to resolve the IDM tenant of the token issuer",
+					Status: http.StatusInternalServerError,
+				})
 				return
 			}
 
 			authHeader, err := pmcontext.GetAuthHeaderFromContext(ctx)
 			if err != nil {
 				log.Debug().Err(err).Msg("No Token info found in context")
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				writeProblem(w, Problem{
+					Type:   ProblemTypeUnauthenticated,
+					Title:  http.StatusText(http.StatusUnauthorized),
+					Status: http.StatusUnauthorized,
+					Detail: "the request carries no authorization header",
+				})
 				return
 			}
 
@@ -73,11 +87,20 @@ func (m *Middleware) SetKCPUserContext() func(http.Handler) http.Handler {
 			allowed, err := checkToken(ctx, authHeader, subdomain, m.restcfg)
 			if err != nil {
 				log.Error().Err(err).Msg("Error while checking auth")
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				writeProblem(w, Problem{
+					Type:   ProblemTypeAuthorizationCheck,
+					Title:  "Failed to verify access to the organization",
+					Status: http.StatusInternalServerError,
+				})
 				return
 			}
 			if !allowed {
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				writeProblem(w, Problem{
+					Type:   ProblemTypeUnauthorized,
+					Title:  http.StatusText(http.StatusUnauthorized),
+					Status: http.StatusUnauthorized,
+					Detail: fmt.Sprintf("the token is not valid for organization %s", subdomain),
+				})
 				return
 			}
 
@@ -2,6 +2,7 @@ package kcp
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,7 @@ import (
 	pmjwt "github.com/platform-mesh/golang-commons/jwt"
 	"github.com/platform-mesh/golang-commons/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"k8s.io/client-go/rest"
 
 	appcontext "github.com/platform-mesh/iam-service/pkg/context"
@@ -35,6 +37,17 @@ func (m *mockIDMTenantRetriever) GetIDMTenant(issuer string) (string, error) {
 
 var _ idm.IDMTenantRetriever = (*mockIDMTenantRetriever)(nil)
 
+// decodeProblem asserts that the response is a problem details object and decodes it
+func decodeProblem(t *testing.T, rr *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+	var problem Problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, rr.Code, problem.Status)
+	return problem
+}
+
 func TestNew(t *testing.T) {
 	mockTenantRetriever := &mockIDMTenantRetriever{}
 	log, _ := logger.New(logger.Config{Level: "debug"})
@@ -138,6 +151,9 @@ func TestSetKCPUserContext_NoWebTokenInContext(t *testing.T) {
 	wrappedHandler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, ProblemTypeUnauthenticated, problem.Type)
 }
 
 // TestGetKCPInfosForContext is removed as the method was refactored away
@@ -615,6 +631,12 @@ func TestSetKCPUserContext_IDMTenantRetrievalError(t *testing.T) {
 	wrappedHandler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, ProblemTypeTenantResolution, problem.Type)
+	assert.Equal(t, "Failed to resolve the IDM tenant of the token issuer", problem.Title)
+	// Internal errors are not exposed to clients
+	assert.Empty(t, problem.Detail)
 }
 
 // Test SetKCPUserContext with missing auth header
@@ -645,6 +667,10 @@ func TestSetKCPUserContext_MissingAuthHeader(t *testing.T) {
 	wrappedHandler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, ProblemTypeUnauthenticated, problem.Type)
+	assert.Equal(t, "Unauthorized", problem.Title)
 }
 
 // Test SetKCPUserContext with token check failure
@@ -686,6 +712,11 @@ func TestSetKCPUserContext_TokenCheckFailure(t *testing.T) {
 	wrappedHandler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, ProblemTypeUnauthorized, problem.Type)
+	assert.Equal(t, "Unauthorized", problem.Title)
+	assert.Equal(t, "the token is not valid for organization test-org", problem.Detail)
 }
 
 // Test SetKCPUserContext with token check error (server error)
@@ -722,6 +753,10 @@ func TestSetKCPUserContext_TokenCheckError(t *testing.T) {
 	wrappedHandler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, ProblemTypeAuthorizationCheck, problem.Type)
+	assert.Equal(t, "Failed to verify access to the organization", problem.Title)
 }
 
 // Test SetKCPUserContext with different subdomain patterns
@@ -0,0 +1,32 @@
+package kcp
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem types identifying why the middleware rejected a request
+const (
+	ProblemTypeUnauthenticated    = "urn:platform-mesh:iam-service:problem:unauthenticated"
+	ProblemTypeUnauthorized       = "urn:platform-mesh:iam-service:problem:unauthorized"
+	ProblemTypeTenantResolution   = "urn:platform-mesh:iam-service:problem:tenant-resolution-failed"
+	ProblemTypeAuthorizationCheck = "urn:platform-mesh:iam-service:problem:authorization-check-failed"
+)
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// writeProblem writes the problem as application/problem+json response with its status
+func writeProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
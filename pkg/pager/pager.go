@@ -1,6 +1,8 @@
 package pager
 
 import (
+	"github.com/platform-mesh/golang-commons/errors"
+
 	"github.com/platform-mesh/iam-service/pkg/config"
 	"github.com/platform-mesh/iam-service/pkg/graph"
 )
@@ -12,12 +14,16 @@ type Pager interface {
 
 	// PaginateUsers applies pagination to a slice of users
 	PaginateUsers(allUsers []*graph.User, page *graph.PageInput, totalCount int) ([]*graph.User, *graph.PageInfo)
+
+	// ValidatePage rejects page inputs requesting more entries than the configured maximum limit
+	ValidatePage(page *graph.PageInput) error
 }
 
 // DefaultPager implements the Pager interface with standard pagination logic
 type DefaultPager struct {
 	defaultLimit int
 	defaultPage  int
+	maxLimit     int
 }
 
 // NewDefaultPager creates a new DefaultPager with default values
@@ -25,6 +31,7 @@ func NewDefaultPager() *DefaultPager {
 	return &DefaultPager{
 		defaultLimit: 10,
 		defaultPage:  1,
+		maxLimit:     0,
 	}
 }
 
@@ -33,7 +40,20 @@ func NewPager(cfg *config.ServiceConfig) *DefaultPager {
 	return &DefaultPager{
 		defaultLimit: cfg.Pagination.DefaultLimit,
 		defaultPage:  cfg.Pagination.DefaultPage,
+		maxLimit:     cfg.Pagination.MaxLimit,
+	}
+}
+
+// ValidatePage returns an error if the requested limit exceeds the maximum limit
+// A missing limit falls back to the default limit and is always valid, a maximum limit of 0 disables the check
+func (p *DefaultPager) ValidatePage(page *graph.PageInput) error {
+	if page == nil || page.Limit == nil || p.maxLimit <= 0 {
+		return nil
+	}
+	if *page.Limit > p.maxLimit {
+		return errors.New("page limit %d exceeds the maximum limit of %d", *page.Limit, p.maxLimit)
 	}
+	return nil
 }
 
 // PaginateUserRoles applies pagination logic to the user roles list and returns the paginated slice and PageInfo
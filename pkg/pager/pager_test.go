@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/platform-mesh/iam-service/pkg/config"
 	"github.com/platform-mesh/iam-service/pkg/graph"
 )
 
@@ -358,6 +359,31 @@ func TestDefaultPager_PaginateUsers_LastPage(t *testing.T) {
 	}
 }
 
+func TestDefaultPager_ValidatePage(t *testing.T) {
+	pager := NewDefaultPager()
+
+	// Missing limits fall back to the default limit
+	assert.NoError(t, pager.ValidatePage(nil))
+	assert.NoError(t, pager.ValidatePage(&graph.PageInput{}))
+
+	// The maximum limit is disabled by default
+	limit := 1000
+	assert.NoError(t, pager.ValidatePage(&graph.PageInput{Limit: &limit}))
+}
+
+func TestNewPager_ValidatePage(t *testing.T) {
+	limit := 1000
+
+	pager := NewPager(&config.ServiceConfig{Pagination: config.PaginationConfig{DefaultLimit: 10, DefaultPage: 1, MaxLimit: 500}})
+	err := pager.ValidatePage(&graph.PageInput{Limit: &limit})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum limit of 500")
+
+	// A max limit of 0 disables the check
+	pager = NewPager(&config.ServiceConfig{Pagination: config.PaginationConfig{DefaultLimit: 10, DefaultPage: 1}})
+	assert.NoError(t, pager.ValidatePage(&graph.PageInput{Limit: &limit}))
+}
+
 // Helper function to create test user roles data
 func createTestUserRoles(count int) []*graph.UserRoles {
 	userRoles := make([]*graph.UserRoles, count)
@@ -0,0 +1,45 @@
+package redact
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// visibleChars is the number of leading characters of the local part that stay readable
+const visibleChars = 3
+
+const mask = "***"
+
+// disabled turns redaction off, it is only meant to be set for local development
+var disabled atomic.Bool
+
+// SetEnabled enables or disables redaction for the whole process. Redaction is enabled by default.
+func SetEnabled(enabled bool) {
+	disabled.Store(!enabled)
+}
+
+// Email returns a redacted version of an email for logs and error messages to avoid leaking PII.
+// The local part is masked after its first 3 characters while the domain is kept,
+// e.g. "jane.doe@example.com" becomes "jan***@example.com".
+// Values without an @ are masked the same way as a local part.
+func Email(email string) string {
+	if email == "" || disabled.Load() {
+		return email
+	}
+
+	local, domain, found := strings.Cut(email, "@")
+	redacted := redactLocal(local)
+	if !found {
+		return redacted
+	}
+	return redacted + "@" + domain
+}
+
+// redactLocal keeps the first characters of a local part and masks the rest.
+// Parts that are too short to be partially shown are masked completely.
+func redactLocal(local string) string {
+	if len(local) <= visibleChars {
+		return mask
+	}
+	return local[:visibleChars] + mask
+}
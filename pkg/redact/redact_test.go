@@ -0,0 +1,36 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "email", input: "jane.doe@example.com", expected: "jan***@example.com"},
+		{name: "short local part", input: "jo@example.com", expected: "***@example.com"},
+		{name: "empty local part", input: "@example.com", expected: "***@example.com"},
+		{name: "without @", input: "user-id-123", expected: "use***"},
+		{name: "short value without @", input: "abc", expected: "***"},
+		{name: "empty string", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Email(tt.input))
+		})
+	}
+}
+
+func TestEmail_Disabled(t *testing.T) {
+	SetEnabled(false)
+	t.Cleanup(func() { SetEnabled(true) })
+
+	assert.Equal(t, "jane.doe@example.com", Email("jane.doe@example.com"))
+	assert.Equal(t, "abc", Email("abc"))
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"github.com/platform-mesh/iam-service/pkg/graph"
 )
@@ -9,9 +10,24 @@ import (
 type ResolverService interface {
 	Me(ctx context.Context) (*graph.User, error)
 	User(ctx context.Context, userID string) (*graph.User, error)
-	Users(ctx context.Context, context graph.ResourceContext, roleFilters []string, sortBy *graph.SortByInput, page *graph.PageInput) (*graph.UserConnection, error)
+	Users(ctx context.Context, context graph.ResourceContext, roleFilters []string, sortBy *graph.SortByInput, page *graph.PageInput, excludeSelf, effectiveRoles bool) (*graph.UserConnection, error)
 	Roles(ctx context.Context, context graph.ResourceContext) ([]*graph.Role, error)
 	AssignRolesToUsers(ctx context.Context, context graph.ResourceContext, changes []*graph.UserRoleChange, invites []*graph.InviteInput) (*graph.RoleAssignmentResult, error)
 	RemoveRole(ctx context.Context, context graph.ResourceContext, input graph.RemoveRoleInput) (*graph.RoleRemovalResult, error)
+	RefreshUserProfiles(ctx context.Context, context graph.ResourceContext) (int, error)
 	KnownUsers(ctx context.Context, sortBy *graph.SortByInput, page *graph.PageInput) (*graph.UserConnection, error)
+	UserPermissions(ctx context.Context, context graph.ResourceContext, userID string) ([]string, error)
+	PreviewRoleAssignments(ctx context.Context, context graph.ResourceContext, changes []*graph.UserRoleChange) ([]*graph.RoleAssignmentPreview, error)
+	Permissions(ctx context.Context, context graph.ResourceContext) ([]string, error)
+	MigrateRoleAssignments(ctx context.Context, context graph.ResourceContext, target *graph.Resource, deleteSource bool) error
+	ExportRoleAssignments(ctx context.Context, context graph.ResourceContext) ([]*graph.RoleAssignmentTuple, error)
+	ImportRoleAssignments(ctx context.Context, context graph.ResourceContext, tuples []*graph.RoleAssignmentTupleInput) error
+	AuditUserRoles(ctx context.Context, context graph.ResourceContext, userID string) (*graph.RoleAudit, error)
+	RoleAssignmentsAt(ctx context.Context, context graph.ResourceContext, at time.Time) ([]*graph.UserRoles, error)
+	ReconcileUsers(ctx context.Context, context graph.ResourceContext, removeMissing bool) (*graph.ReconcileReport, error)
+}
+
+// PermissionChecker checks permissions of the calling user on a resource
+type PermissionChecker interface {
+	CanI(ctx context.Context, context graph.ResourceContext, permission string) (bool, error)
 }
@@ -2,6 +2,9 @@ package pm
 
 import (
 	"context"
+	"slices"
+	"strings"
+	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	pmcontext "github.com/platform-mesh/golang-commons/context"
@@ -21,8 +24,6 @@ import (
 
 var _ api.ResolverService = (*Service)(nil)
 
-const ownerRoleID = "owner"
-
 type Service struct {
 	fgaService      *fga.Service
 	keycloakService *keycloak.Service
@@ -57,17 +58,46 @@ func (s *Service) User(ctx context.Context, userID string) (*graph.User, error)
 	return s.transformer.Transform(user), nil
 }
 
-func (s *Service) Users(ctx context.Context, rctx graph.ResourceContext, roleFilters []string, sortBy *graph.SortByInput, page *graph.PageInput) (*graph.UserConnection, error) {
-	ownersCount, err := s.fgaService.CountUsersForRole(ctx, rctx, ownerRoleID)
+func (s *Service) Users(ctx context.Context, rctx graph.ResourceContext, roleFilters []string, sortBy *graph.SortByInput, page *graph.PageInput, excludeSelf, effectiveRoles bool) (*graph.UserConnection, error) {
+	if err := s.pager.ValidatePage(page); err != nil {
+		return nil, err
+	}
+
+	var selfEmail string
+	if excludeSelf {
+		webToken, err := pmcontext.GetWebTokenFromContext(ctx)
+		if err != nil {
+			return nil, serrors.ErrInternal
+		}
+		selfEmail = webToken.Mail
+	}
+
+	ownerRole, err := s.fgaService.OwnerRole(rctx)
 	if err != nil {
 		return nil, err
 	}
 
-	allUserRoles, err := s.fgaService.ListUsers(ctx, rctx, roleFilters)
+	owners, err := s.fgaService.ListUsers(ctx, rctx, []string{ownerRole})
 	if err != nil {
 		return nil, err
 	}
 
+	listUsers := s.fgaService.ListUsers
+	if effectiveRoles {
+		listUsers = s.fgaService.ListUsersWithEffectiveRoles
+	}
+	allUserRoles, err := listUsers(ctx, rctx, roleFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	if excludeSelf {
+		// Exclude before counting and pagination so that all counts match the returned list
+		owners = withoutUser(owners, selfEmail)
+		allUserRoles = withoutUser(allUserRoles, selfEmail)
+	}
+	ownersCount := len(owners)
+
 	err = s.keycloakService.EnrichUserRoles(ctx, allUserRoles)
 	if err != nil {
 		return nil, err
@@ -89,7 +119,19 @@ func (s *Service) Users(ctx context.Context, rctx graph.ResourceContext, roleFil
 	}, nil
 }
 
+// withoutUser removes the user with the given email from the list
+// FGA identifies users by email, which is compared case-insensitively
+func withoutUser(userRoles []*graph.UserRoles, email string) []*graph.UserRoles {
+	return slices.DeleteFunc(userRoles, func(ur *graph.UserRoles) bool {
+		return ur.User != nil && strings.EqualFold(ur.User.Email, email)
+	})
+}
+
 func (s *Service) KnownUsers(ctx context.Context, sortBy *graph.SortByInput, page *graph.PageInput) (*graph.UserConnection, error) {
+	if err := s.pager.ValidatePage(page); err != nil {
+		return nil, err
+	}
+
 	// Fetch all known users from Keycloak
 	users, err := s.keycloakService.GetUsers(ctx)
 	if err != nil {
@@ -120,6 +162,17 @@ func (s *Service) KnownUsers(ctx context.Context, sortBy *graph.SortByInput, pag
 	}, nil
 }
 
+// RefreshUserProfiles reloads the Keycloak profiles of all users with a role on the resource,
+// bypassing the user cache. Returns the number of refreshed users.
+func (s *Service) RefreshUserProfiles(ctx context.Context, rctx graph.ResourceContext) (int, error) {
+	userRoles, err := s.fgaService.ListUsers(ctx, rctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.keycloakService.RefreshUserRoles(ctx, userRoles)
+}
+
 func (s *Service) AssignRolesToUsers(ctx context.Context, rCtx graph.ResourceContext, changes []*graph.UserRoleChange, invites []*graph.InviteInput) (*graph.RoleAssignmentResult, error) {
 	return s.fgaService.AssignRolesToUsers(ctx, rCtx, changes, invites)
 }
@@ -128,10 +181,134 @@ func (s *Service) RemoveRole(ctx context.Context, rCtx graph.ResourceContext, in
 	return s.fgaService.RemoveRole(ctx, rCtx, input)
 }
 
+func (s *Service) UserPermissions(ctx context.Context, rCtx graph.ResourceContext, userID string) ([]string, error) {
+	return s.fgaService.UserPermissions(ctx, rCtx, userID)
+}
+
+func (s *Service) PreviewRoleAssignments(ctx context.Context, rCtx graph.ResourceContext, changes []*graph.UserRoleChange) ([]*graph.RoleAssignmentPreview, error) {
+	return s.fgaService.PreviewRoleAssignments(ctx, rCtx, changes)
+}
+
+func (s *Service) Permissions(ctx context.Context, rCtx graph.ResourceContext) ([]string, error) {
+	return s.fgaService.Permissions(ctx, rCtx)
+}
+
+func (s *Service) MigrateRoleAssignments(ctx context.Context, rCtx graph.ResourceContext, target *graph.Resource, deleteSource bool) error {
+	return s.fgaService.MigrateRoleAssignments(ctx, rCtx, target, deleteSource)
+}
+
+func (s *Service) ExportRoleAssignments(ctx context.Context, rCtx graph.ResourceContext) ([]*graph.RoleAssignmentTuple, error) {
+	tupleKeys, err := s.fgaService.ExportRoleAssignments(ctx, rCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*graph.RoleAssignmentTuple, 0, len(tupleKeys))
+	for _, tupleKey := range tupleKeys {
+		result = append(result, &graph.RoleAssignmentTuple{User: tupleKey.User, Relation: tupleKey.Relation, Object: tupleKey.Object})
+	}
+	return result, nil
+}
+
+func (s *Service) ImportRoleAssignments(ctx context.Context, rCtx graph.ResourceContext, tuples []*graph.RoleAssignmentTupleInput) error {
+	tupleKeys := make([]*openfgav1.TupleKey, 0, len(tuples))
+	for _, tuple := range tuples {
+		tupleKeys = append(tupleKeys, &openfgav1.TupleKey{User: tuple.User, Relation: tuple.Relation, Object: tuple.Object})
+	}
+	return s.fgaService.ImportRoleAssignments(ctx, rCtx, tupleKeys)
+}
+
+func (s *Service) AuditUserRoles(ctx context.Context, rCtx graph.ResourceContext, userID string) (*graph.RoleAudit, error) {
+	audit, err := s.fgaService.AuditUserRoles(ctx, rCtx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	redundant := make([]*graph.RedundantRole, 0, len(audit.Redundant))
+	for _, role := range audit.Redundant {
+		redundant = append(redundant, &graph.RedundantRole{Role: role.Role, SupersededBy: role.SupersededBy})
+	}
+	return &graph.RoleAudit{
+		UserID:     audit.UserID,
+		Roles:      audit.Roles,
+		Duplicates: audit.Duplicates,
+		Redundant:  redundant,
+	}, nil
+}
+
+// RoleAssignmentsAt returns the users with roles on the resource at the given time, enriched
+// with their current Keycloak profiles where those still exist
+func (s *Service) RoleAssignmentsAt(ctx context.Context, rCtx graph.ResourceContext, at time.Time) ([]*graph.UserRoles, error) {
+	userRoles, err := s.fgaService.RoleAssignmentsAt(ctx, rCtx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.keycloakService.EnrichUserRoles(ctx, userRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	s.userSorter.SortUserRoles(userRoles, nil)
+	for _, ur := range userRoles {
+		ur.User = s.transformer.Transform(ur.User)
+	}
+	return userRoles, nil
+}
+
+func (s *Service) ReconcileUsers(ctx context.Context, rCtx graph.ResourceContext, removeMissing bool) (*graph.ReconcileReport, error) {
+	report, err := s.fgaService.ReconcileUsers(ctx, rCtx, removeMissing)
+	if err != nil {
+		return nil, err
+	}
+
+	removals := make([]*graph.UserRemoval, 0, len(report.Removals))
+	for _, removal := range report.Removals {
+		result := &graph.UserRemoval{UserID: removal.UserID, RemovedRoles: removal.RemovedRoles}
+		if removal.Err != nil {
+			errMsg := removal.Err.Error()
+			result.Error = &errMsg
+		}
+		removals = append(removals, result)
+	}
+	return &graph.ReconcileReport{
+		Checked:   report.Checked,
+		Missing:   report.Missing,
+		Unchecked: report.Unchecked,
+		Removals:  removals,
+	}, nil
+}
+
 func (s *Service) Roles(ctx context.Context, context graph.ResourceContext) ([]*graph.Role, error) {
 	return s.fgaService.GetRoles(ctx, context)
 }
 
+// ValidateModel checks that the authorization models of the organizations define the configured roles
+func (s *Service) ValidateModel(ctx context.Context, orgIDs []string) error {
+	return s.fgaService.ValidateModel(ctx, orgIDs)
+}
+
+// WarmCaches loads the caches of the configured organizations ahead of the first request.
+// Keycloak realms are named after their organization. Stops early if ctx is canceled.
+func (s *Service) WarmCaches(ctx context.Context, cfg config.CacheWarmupConfig) error {
+	if err := s.fgaService.WarmCaches(ctx, cfg.Organizations); err != nil {
+		return err
+	}
+	if !cfg.KeycloakUsers {
+		return nil
+	}
+
+	for _, org := range cfg.Organizations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.keycloakService.WarmUserCache(ctx, org); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func NewResolverService(fgaClient openfgav1.OpenFGAServiceClient, service *keycloak.Service, cfg *config.ServiceConfig, mgr mcmanager.Manager) (*Service, error) {
 	// Create workspace client factory
 	wsClientFactory := workspace.NewClientFactory(mgr)
@@ -17,6 +17,7 @@ import (
 	"github.com/platform-mesh/iam-service/pkg/keycloak"
 	"github.com/platform-mesh/iam-service/pkg/pager"
 	"github.com/platform-mesh/iam-service/pkg/resolver"
+	serrors "github.com/platform-mesh/iam-service/pkg/resolver/errors"
 	"github.com/platform-mesh/iam-service/pkg/roles"
 	"github.com/platform-mesh/iam-service/pkg/sorter"
 )
@@ -44,6 +45,7 @@ func createTestResolverService(t *testing.T) (*Service, *mocks.OpenFGAServiceCli
 		Pagination: config.PaginationConfig{
 			DefaultLimit: 10,
 			DefaultPage:  1,
+			MaxLimit:     100,
 		},
 		Keycloak: config.KeycloakConfig{
 			Cache: config.KeycloakCacheConfig{
@@ -280,7 +282,7 @@ func TestNew(t *testing.T) {
 	mockLogger, err := logger.New(logger.Config{})
 	assert.NoError(t, err)
 
-	resolverInstance := resolver.New(realService, mockLogger)
+	resolverInstance := resolver.New(realService, nil, mockLogger)
 
 	assert.NotNil(t, resolverInstance)
 	// Note: we can't directly access svc and logger fields as they may be private
@@ -358,3 +360,86 @@ func TestService_User_DirectCall(t *testing.T) {
 	// This covers the User method implementation
 	assert.NotNil(t, realService.keycloakService)
 }
+
+func TestService_Users_LimitAboveMax(t *testing.T) {
+	// No FGA expectations are set, the request must be rejected before any call
+	realService, _ := createTestResolverService(t)
+
+	ctx := context.Background()
+	resourceContext := graph.ResourceContext{
+		Group:    "test.group",
+		Kind:     "TestResource",
+		Resource: &graph.Resource{Name: "test-resource"},
+	}
+
+	_, err := realService.Users(ctx, resourceContext, nil, nil, &graph.PageInput{Limit: ptr.To(101)}, false, false)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum limit of 100")
+}
+
+func TestService_KnownUsers_LimitAboveMax(t *testing.T) {
+	realService, _ := createTestResolverService(t)
+
+	_, err := realService.KnownUsers(context.Background(), nil, &graph.PageInput{Limit: ptr.To(101)})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum limit of 100")
+}
+
+func TestService_RefreshUserProfiles_ListUsersError(t *testing.T) {
+	// Without a KCP context the users of the resource can't be listed, Keycloak must not be touched
+	realService, _ := createTestResolverService(t)
+
+	resourceContext := graph.ResourceContext{
+		Group:    "test.group",
+		Kind:     "TestResource",
+		Resource: &graph.Resource{Name: "test-resource"},
+	}
+
+	count, err := realService.RefreshUserProfiles(context.Background(), resourceContext)
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestService_Users_ExcludeSelfWithoutWebToken(t *testing.T) {
+	// No FGA expectations are set, the request must be rejected before any call
+	realService, _ := createTestResolverService(t)
+
+	resourceContext := graph.ResourceContext{
+		Group:    "test.group",
+		Kind:     "TestResource",
+		Resource: &graph.Resource{Name: "test-resource"},
+	}
+
+	_, err := realService.Users(context.Background(), resourceContext, nil, nil, nil, true, false)
+
+	assert.ErrorIs(t, err, serrors.ErrInternal)
+}
+
+func TestWithoutUser(t *testing.T) {
+	owner := &graph.UserRoles{
+		User:  &graph.User{Email: "me@example.com"},
+		Roles: []*graph.Role{{ID: "owner"}},
+	}
+	other := &graph.UserRoles{
+		User:  &graph.User{Email: "other@example.com"},
+		Roles: []*graph.Role{{ID: "owner"}},
+	}
+	member := &graph.UserRoles{
+		User:  &graph.User{Email: "member@example.com"},
+		Roles: []*graph.Role{{ID: "member"}},
+	}
+
+	// The caller is dropped from both the owners and the user list, so both counts decrement
+	owners := withoutUser([]*graph.UserRoles{owner, other}, "Me@Example.com")
+	assert.Equal(t, []*graph.UserRoles{other}, owners)
+
+	users := withoutUser([]*graph.UserRoles{member, owner, other}, "me@example.com")
+	assert.Equal(t, []*graph.UserRoles{member, other}, users)
+
+	// Callers without a role on the resource leave the list unchanged
+	users = withoutUser([]*graph.UserRoles{member, other}, "me@example.com")
+	assert.Equal(t, []*graph.UserRoles{member, other}, users)
+}
@@ -9,13 +9,15 @@ import (
 //go:generate go run github.com/99designs/gqlgen@v0.17.81 generate
 
 type Resolver struct {
-	svc    api.ResolverService
-	logger *logger.Logger
+	svc         api.ResolverService
+	permissions api.PermissionChecker
+	logger      *logger.Logger
 }
 
-func New(svc api.ResolverService, logger *logger.Logger) *Resolver {
+func New(svc api.ResolverService, permissions api.PermissionChecker, logger *logger.Logger) *Resolver {
 	return &Resolver{
-		svc:    svc,
-		logger: logger,
+		svc:         svc,
+		permissions: permissions,
+		logger:      logger,
 	}
 }
@@ -6,8 +6,10 @@ package resolver
 
 import (
 	"context"
+	"time"
 
 	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
 // AssignRolesToUsers is the resolver for the assignRolesToUsers field.
@@ -20,14 +22,46 @@ func (r *mutationResolver) RemoveRole(ctx context.Context, context graph.Resourc
 	return r.svc.RemoveRole(ctx, context, input)
 }
 
+// RefreshUserProfiles is the resolver for the refreshUserProfiles field.
+func (r *mutationResolver) RefreshUserProfiles(ctx context.Context, context graph.ResourceContext) (int, error) {
+	return r.svc.RefreshUserProfiles(ctx, context)
+}
+
+// MigrateRoleAssignments is the resolver for the migrateRoleAssignments field.
+func (r *mutationResolver) MigrateRoleAssignments(ctx context.Context, context graph.ResourceContext, target graph.Resource, deleteSource *bool) (bool, error) {
+	// The directive only authorizes the source resource
+	targetCtx := graph.ResourceContext{Group: context.Group, Kind: context.Kind, Resource: &target, AccountPath: context.AccountPath}
+	allowed, err := r.permissions.CanI(ctx, targetCtx, "manage_iam_roles")
+	if err != nil {
+		return false, err
+	}
+	if !allowed {
+		return false, gqlerror.Errorf("unauthorized")
+	}
+
+	err = r.svc.MigrateRoleAssignments(ctx, context, &target, deleteSource != nil && *deleteSource)
+	return err == nil, err
+}
+
+// ImportRoleAssignments is the resolver for the importRoleAssignments field.
+func (r *mutationResolver) ImportRoleAssignments(ctx context.Context, context graph.ResourceContext, tuples []*graph.RoleAssignmentTupleInput) (bool, error) {
+	err := r.svc.ImportRoleAssignments(ctx, context, tuples)
+	return err == nil, err
+}
+
+// ReconcileUsers is the resolver for the reconcileUsers field.
+func (r *mutationResolver) ReconcileUsers(ctx context.Context, context graph.ResourceContext, removeMissing *bool) (*graph.ReconcileReport, error) {
+	return r.svc.ReconcileUsers(ctx, context, removeMissing != nil && *removeMissing)
+}
+
 // Roles is the resolver for the roles field.
 func (r *queryResolver) Roles(ctx context.Context, context graph.ResourceContext) ([]*graph.Role, error) {
 	return r.svc.Roles(ctx, context)
 }
 
 // Users is the resolver for the users field.
-func (r *queryResolver) Users(ctx context.Context, context graph.ResourceContext, roleFilters []string, sortBy *graph.SortByInput, page *graph.PageInput) (*graph.UserConnection, error) {
-	return r.svc.Users(ctx, context, roleFilters, sortBy, page)
+func (r *queryResolver) Users(ctx context.Context, context graph.ResourceContext, roleFilters []string, sortBy *graph.SortByInput, page *graph.PageInput, excludeSelf *bool, effectiveRoles *bool) (*graph.UserConnection, error) {
+	return r.svc.Users(ctx, context, roleFilters, sortBy, page, excludeSelf != nil && *excludeSelf, effectiveRoles != nil && *effectiveRoles)
 }
 
 // KnownUsers is the resolver for the knownUsers field.
@@ -45,6 +79,41 @@ func (r *queryResolver) Me(ctx context.Context) (*graph.User, error) {
 	return r.svc.Me(ctx)
 }
 
+// CanI is the resolver for the canI field.
+func (r *queryResolver) CanI(ctx context.Context, context graph.ResourceContext, permission string) (bool, error) {
+	return r.permissions.CanI(ctx, context, permission)
+}
+
+// UserPermissions is the resolver for the userPermissions field.
+func (r *queryResolver) UserPermissions(ctx context.Context, context graph.ResourceContext, userID string) ([]string, error) {
+	return r.svc.UserPermissions(ctx, context, userID)
+}
+
+// PreviewRoleAssignments is the resolver for the previewRoleAssignments field.
+func (r *queryResolver) PreviewRoleAssignments(ctx context.Context, context graph.ResourceContext, changes []*graph.UserRoleChange) ([]*graph.RoleAssignmentPreview, error) {
+	return r.svc.PreviewRoleAssignments(ctx, context, changes)
+}
+
+// Permissions is the resolver for the permissions field.
+func (r *queryResolver) Permissions(ctx context.Context, context graph.ResourceContext) ([]string, error) {
+	return r.svc.Permissions(ctx, context)
+}
+
+// ExportRoleAssignments is the resolver for the exportRoleAssignments field.
+func (r *queryResolver) ExportRoleAssignments(ctx context.Context, context graph.ResourceContext) ([]*graph.RoleAssignmentTuple, error) {
+	return r.svc.ExportRoleAssignments(ctx, context)
+}
+
+// AuditUserRoles is the resolver for the auditUserRoles field.
+func (r *queryResolver) AuditUserRoles(ctx context.Context, context graph.ResourceContext, userID string) (*graph.RoleAudit, error) {
+	return r.svc.AuditUserRoles(ctx, context, userID)
+}
+
+// RoleAssignmentsAt is the resolver for the roleAssignmentsAt field.
+func (r *queryResolver) RoleAssignmentsAt(ctx context.Context, context graph.ResourceContext, at time.Time) ([]*graph.UserRoles, error) {
+	return r.svc.RoleAssignmentsAt(ctx, context, at)
+}
+
 // Mutation returns graph.MutationResolver implementation.
 func (r *Resolver) Mutation() graph.MutationResolver { return &mutationResolver{r} }
 
@@ -0,0 +1,63 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/platform-mesh/golang-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/platform-mesh/iam-service/pkg/graph"
+	"github.com/platform-mesh/iam-service/pkg/resolver/api"
+)
+
+// migrationService records the migrations it is asked to perform
+type migrationService struct {
+	api.ResolverService
+	targets []string
+}
+
+func (s *migrationService) MigrateRoleAssignments(_ context.Context, _ graph.ResourceContext, target *graph.Resource, _ bool) error {
+	s.targets = append(s.targets, target.Name)
+	return nil
+}
+
+// resourcePermissions grants the permission on the named resources only
+type resourcePermissions map[string]bool
+
+func (p resourcePermissions) CanI(_ context.Context, rctx graph.ResourceContext, _ string) (bool, error) {
+	return p[rctx.Resource.Name], nil
+}
+
+func TestMigrateRoleAssignments_ChecksTarget(t *testing.T) {
+	log, err := logger.New(logger.DefaultConfig())
+	require.NoError(t, err)
+	rctx := graph.ResourceContext{Group: "core.platform-mesh.io", Kind: "Account", Resource: &graph.Resource{Name: "source"}, AccountPath: "root:org"}
+
+	tests := []struct {
+		name        string
+		permissions resourcePermissions
+		migrated    bool
+	}{
+		{name: "target allowed", permissions: resourcePermissions{"source": true, "target": true}, migrated: true},
+		{name: "target denied", permissions: resourcePermissions{"source": true}, migrated: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &migrationService{}
+			r := New(svc, tt.permissions, log)
+
+			ok, err := r.Mutation().MigrateRoleAssignments(context.Background(), rctx, graph.Resource{Name: "target"}, nil)
+
+			assert.Equal(t, tt.migrated, ok)
+			if tt.migrated {
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"target"}, svc.targets)
+			} else {
+				assert.EqualError(t, err, "input: unauthorized")
+				assert.Empty(t, svc.targets)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package roles
 
 import (
+	"cmp"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/platform-mesh/golang-commons/errors"
@@ -16,6 +18,9 @@ type RoleDefinition struct {
 	ID          string `yaml:"id"`
 	DisplayName string `yaml:"displayName"`
 	Description string `yaml:"description"`
+	// Priority ranks the role among the roles of its group resource, higher priorities outrank lower ones.
+	// Roles of equal priority keep the order of the roles file.
+	Priority int `yaml:"priority"`
 }
 
 // GroupResourceRoles represents roles for a specific group resource
@@ -32,6 +37,8 @@ type RolesConfig struct {
 // RolesRetriever interface for retrieving roles
 type RolesRetriever interface {
 	GetRoleDefinitions(resourceContext graph.ResourceContext) ([]RoleDefinition, error)
+	// GroupResources returns the group resources roles are defined for, as "<group>/<kind>" or "<kind>" for the core group
+	GroupResources() []string
 }
 
 // FileBasedRolesRetriever implements RolesRetriever by reading from a YAML file
@@ -53,6 +60,13 @@ func NewFileBasedRolesRetriever(filePath string) (*FileBasedRolesRetriever, erro
 		return nil, errors.Wrap(err, "failed to unmarshal roles YAML from file %s", filePath)
 	}
 
+	// Consumers rely on the roles being ordered by rank
+	for _, groupRoles := range config.Roles {
+		slices.SortStableFunc(groupRoles.Roles, func(a, b RoleDefinition) int {
+			return cmp.Compare(b.Priority, a.Priority)
+		})
+	}
+
 	retriever := &FileBasedRolesRetriever{
 		filePath: filePath,
 		config:   &config,
@@ -60,7 +74,7 @@ func NewFileBasedRolesRetriever(filePath string) (*FileBasedRolesRetriever, erro
 	return retriever, nil
 }
 
-// GetRoleDefinitions returns the full role definitions for a given group resource
+// GetRoleDefinitions returns the full role definitions for a given group resource, highest ranked role first
 func (r *FileBasedRolesRetriever) GetRoleDefinitions(rctx graph.ResourceContext) ([]RoleDefinition, error) {
 	if r.config == nil {
 		return nil, errors.New("roles configuration not loaded")
@@ -77,6 +91,19 @@ func (r *FileBasedRolesRetriever) GetRoleDefinitions(rctx graph.ResourceContext)
 	return []RoleDefinition{}, nil
 }
 
+// GroupResources returns the group resources of the roles file in the order they are defined
+func (r *FileBasedRolesRetriever) GroupResources() []string {
+	if r.config == nil {
+		return nil
+	}
+
+	groupResources := make([]string, len(r.config.Roles))
+	for i, groupRoles := range r.config.Roles {
+		groupResources[i] = groupRoles.GroupResource
+	}
+	return groupResources
+}
+
 // GetAvailableRoleIDs is a helper function that extracts role IDs from role definitions
 func GetAvailableRoleIDs(roleDefinitions []RoleDefinition) []string {
 	roleIDs := make([]string, len(roleDefinitions))
@@ -85,3 +112,12 @@ func GetAvailableRoleIDs(roleDefinitions []RoleDefinition) []string {
 	}
 	return roleIDs
 }
+
+// OwnerRoleID returns the ID of the highest ranked role, which owns the resource.
+// Returns an empty string if no roles are defined.
+func OwnerRoleID(roleDefinitions []RoleDefinition) string {
+	if len(roleDefinitions) == 0 {
+		return ""
+	}
+	return roleDefinitions[0].ID
+}
@@ -112,6 +112,40 @@ func TestGetRoleDefinitions_Success(t *testing.T) {
 	assert.Equal(t, "Limited access to resources", definitions[1].Description)
 }
 
+func TestGetRoleDefinitions_OrderedByPriority(t *testing.T) {
+	content := `roles:
+  - groupResource: core.platform-mesh.io/Account
+    roles:
+      - id: member
+        displayName: Member
+      - id: viewer
+        displayName: Viewer
+      - id: admin
+        displayName: Admin
+        priority: 10
+      - id: owner
+        displayName: Owner
+        priority: 5`
+
+	tmpFile := createTempYAMLFile(t, content)
+	defer func() { _ = os.Remove(tmpFile) }()
+
+	retriever, err := NewFileBasedRolesRetriever(tmpFile)
+	require.NoError(t, err)
+
+	definitions, err := retriever.GetRoleDefinitions(graph.ResourceContext{Group: "core.platform-mesh.io", Kind: "Account"})
+	require.NoError(t, err)
+
+	// Roles without priority keep the order of the file
+	assert.Equal(t, []string{"admin", "owner", "member", "viewer"}, GetAvailableRoleIDs(definitions))
+	assert.Equal(t, 10, definitions[0].Priority)
+	assert.Equal(t, "admin", OwnerRoleID(definitions))
+}
+
+func TestOwnerRoleID_NoRoles(t *testing.T) {
+	assert.Empty(t, OwnerRoleID(nil))
+}
+
 func TestGetRoleDefinitions_GroupResourceNotFound(t *testing.T) {
 	content := `roles:
   - groupResource: core.platform-mesh.io/Account
@@ -199,3 +233,22 @@ func createTempYAMLFile(t *testing.T, content string) string {
 
 	return tmpFile.Name()
 }
+
+func TestFileBasedRolesRetriever_GroupResources(t *testing.T) {
+	content := `roles:
+  - groupResource: core.platform-mesh.io/Account
+    roles:
+      - id: owner
+  - groupResource: Namespace
+    roles:
+      - id: owner`
+
+	tmpFile := createTempYAMLFile(t, content)
+	defer func() { _ = os.Remove(tmpFile) }()
+
+	retriever, err := NewFileBasedRolesRetriever(tmpFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"core.platform-mesh.io/Account", "Namespace"}, retriever.GroupResources())
+	assert.Nil(t, (&FileBasedRolesRetriever{}).GroupResources())
+}
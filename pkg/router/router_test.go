@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	pmconfig "github.com/platform-mesh/golang-commons/config"
@@ -31,7 +32,7 @@ func (s *testResolverService) User(ctx context.Context, userID string) (*graph.U
 	return &graph.User{UserID: userID, Email: userID + "@example.com"}, nil
 }
 
-func (s *testResolverService) Users(ctx context.Context, resourceContext graph.ResourceContext, roleFilters []string, sortBy *graph.SortByInput, page *graph.PageInput) (*graph.UserConnection, error) {
+func (s *testResolverService) Users(ctx context.Context, resourceContext graph.ResourceContext, roleFilters []string, sortBy *graph.SortByInput, page *graph.PageInput, excludeSelf, effectiveRoles bool) (*graph.UserConnection, error) {
 	return &graph.UserConnection{
 		Users:    []*graph.UserRoles{},
 		PageInfo: &graph.PageInfo{Count: 0, TotalCount: 0, HasNextPage: false, HasPreviousPage: false},
@@ -50,6 +51,10 @@ func (s *testResolverService) RemoveRole(ctx context.Context, resourceContext gr
 	return &graph.RoleRemovalResult{Success: true, WasAssigned: true}, nil
 }
 
+func (s *testResolverService) RefreshUserProfiles(ctx context.Context, resourceContext graph.ResourceContext) (int, error) {
+	return 0, nil
+}
+
 func (s *testResolverService) KnownUsers(ctx context.Context, sortBy *graph.SortByInput, page *graph.PageInput) (*graph.UserConnection, error) {
 	return &graph.UserConnection{
 		Users:    []*graph.UserRoles{},
@@ -57,6 +62,42 @@ func (s *testResolverService) KnownUsers(ctx context.Context, sortBy *graph.Sort
 	}, nil
 }
 
+func (s *testResolverService) UserPermissions(ctx context.Context, resourceContext graph.ResourceContext, userID string) ([]string, error) {
+	return []string{}, nil
+}
+
+func (s *testResolverService) PreviewRoleAssignments(ctx context.Context, resourceContext graph.ResourceContext, changes []*graph.UserRoleChange) ([]*graph.RoleAssignmentPreview, error) {
+	return []*graph.RoleAssignmentPreview{}, nil
+}
+
+func (s *testResolverService) Permissions(ctx context.Context, resourceContext graph.ResourceContext) ([]string, error) {
+	return []string{}, nil
+}
+
+func (s *testResolverService) MigrateRoleAssignments(ctx context.Context, resourceContext graph.ResourceContext, target *graph.Resource, deleteSource bool) error {
+	return nil
+}
+
+func (s *testResolverService) ExportRoleAssignments(ctx context.Context, resourceContext graph.ResourceContext) ([]*graph.RoleAssignmentTuple, error) {
+	return []*graph.RoleAssignmentTuple{}, nil
+}
+
+func (s *testResolverService) ImportRoleAssignments(ctx context.Context, resourceContext graph.ResourceContext, tuples []*graph.RoleAssignmentTupleInput) error {
+	return nil
+}
+
+func (s *testResolverService) AuditUserRoles(ctx context.Context, resourceContext graph.ResourceContext, userID string) (*graph.RoleAudit, error) {
+	return &graph.RoleAudit{UserID: userID}, nil
+}
+
+func (s *testResolverService) RoleAssignmentsAt(ctx context.Context, resourceContext graph.ResourceContext, at time.Time) ([]*graph.UserRoles, error) {
+	return []*graph.UserRoles{}, nil
+}
+
+func (s *testResolverService) ReconcileUsers(ctx context.Context, resourceContext graph.ResourceContext, removeMissing bool) (*graph.ReconcileReport, error) {
+	return &graph.ReconcileReport{}, nil
+}
+
 // createTestResolver creates a GraphQL resolver for HTTP routing tests
 // Since router tests focus on HTTP behavior (middleware, endpoints) rather than
 // GraphQL business logic, a simple test service implementation is appropriate
@@ -71,7 +112,7 @@ func createTestResolver(t *testing.T) graph.ResolverRoot {
 	}
 
 	// Create GraphQL resolver
-	return resolver.New(resolverService, log)
+	return resolver.New(resolverService, nil, log)
 }
 
 // createEmptyDirectiveRoot returns an empty DirectiveRoot for testing
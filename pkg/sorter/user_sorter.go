@@ -103,11 +103,24 @@ func (s *DefaultUserSorter) SortUsers(users []*graph.User, sortBy *graph.SortByI
 }
 
 // compareUsers compares two users based on the specified field
+// Users with equal values, e.g. invited users without first and last name,
+// are ordered by email and then by user ID to keep the order deterministic
 // Returns:
 //   - negative value if userI < userJ
 //   - zero if userI == userJ
 //   - positive value if userI > userJ
 func (s *DefaultUserSorter) compareUsers(userI, userJ *graph.User, field graph.UserSortField) int {
+	if result := s.compareField(userI, userJ, field); result != 0 {
+		return result
+	}
+	if result := strings.Compare(userI.Email, userJ.Email); result != 0 {
+		return result
+	}
+	return strings.Compare(userI.UserID, userJ.UserID)
+}
+
+// compareField compares two users based on the value of the specified field only
+func (s *DefaultUserSorter) compareField(userI, userJ *graph.User, field graph.UserSortField) int {
 	switch field {
 	case graph.UserSortFieldUserID:
 		return strings.Compare(userI.UserID, userJ.UserID)
@@ -570,6 +570,58 @@ func TestDefaultUserSorter_SortUsers_WithNilValues(t *testing.T) {
 	assert.Equal(t, "Smith", *users[2].LastName)
 }
 
+func TestDefaultUserSorter_SortUserRoles_InviteesOrderedByEmail(t *testing.T) {
+	sorter := NewUserSorter()
+
+	// Invited users are only known by email and have no names yet
+	newUserRoles := func() []*graph.UserRoles {
+		return []*graph.UserRoles{
+			{User: &graph.User{UserID: "user1", Email: "zoe@example.com", FirstName: stringPtr("Zoe"), LastName: stringPtr("Adams")}},
+			{User: &graph.User{Email: "invitee-b@example.com"}},
+			{User: &graph.User{UserID: "user2", Email: "anna@example.com", FirstName: stringPtr("Anna"), LastName: stringPtr("Baker")}},
+			{User: &graph.User{Email: "invitee-a@example.com"}},
+			{User: &graph.User{Email: "invitee-c@example.com"}},
+		}
+	}
+
+	emails := func(userRoles []*graph.UserRoles) []string {
+		result := make([]string, len(userRoles))
+		for i, ur := range userRoles {
+			result[i] = ur.User.Email
+		}
+		return result
+	}
+
+	asc := []string{"invitee-a@example.com", "invitee-b@example.com", "invitee-c@example.com", "zoe@example.com", "anna@example.com"}
+
+	// Repeat to make sure the order doesn't depend on the input order of equal entries
+	for i := 0; i < 10; i++ {
+		userRoles := newUserRoles()
+		sorter.SortUserRoles(userRoles, &graph.SortByInput{Field: graph.UserSortFieldLastName, Direction: graph.SortDirectionAsc})
+		assert.Equal(t, asc, emails(userRoles))
+
+		userRoles = newUserRoles()
+		sorter.SortUserRoles(userRoles, &graph.SortByInput{Field: graph.UserSortFieldLastName, Direction: graph.SortDirectionDesc})
+		assert.Equal(t, []string{"anna@example.com", "zoe@example.com", "invitee-c@example.com", "invitee-b@example.com", "invitee-a@example.com"}, emails(userRoles))
+	}
+}
+
+func TestDefaultUserSorter_SortUsers_EqualValuesOrderedByEmail(t *testing.T) {
+	sorter := NewUserSorter()
+
+	users := []*graph.User{
+		{UserID: "user3", Email: "c@example.com", FirstName: stringPtr("Sam")},
+		{UserID: "user1", Email: "a@example.com", FirstName: stringPtr("Sam")},
+		{UserID: "user2", Email: "b@example.com", FirstName: stringPtr("Sam")},
+	}
+
+	sorter.SortUsers(users, &graph.SortByInput{Field: graph.UserSortFieldFirstName, Direction: graph.SortDirectionAsc})
+
+	assert.Equal(t, "a@example.com", users[0].Email)
+	assert.Equal(t, "b@example.com", users[1].Email)
+	assert.Equal(t, "c@example.com", users[2].Email)
+}
+
 // Helper function to create string pointers
 func stringPtr(s string) *string {
 	return &s